@@ -72,12 +72,10 @@ type handler struct {
 }
 
 func (h *handler) OnPacket(p *packet.Packet) error {
-	fmt.Println(string(p.Payload), " from ", p.Remote)
-	resp := make([]byte, p.Size)
-	copy(resp, p.Payload[:p.Size])
+	fmt.Println(string(p.Payload[:p.Size]), " from ", p.Remote)
 
-	udpServer.Send(resp, p.Remote)
-	return nil
+	// The packet goes back as is, released once sent.
+	return udpServer.SendPacket(p)
 }
 
 func (h *handler) OnError(err error) error {
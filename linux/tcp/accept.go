@@ -0,0 +1,300 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package tcp
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/mailru/easygo/netpoll"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	acceptBatch      = 128
+	minAcceptBackoff = 5 * time.Millisecond
+	maxAcceptBackoff = time.Second
+)
+
+var (
+	// ErrNotSyscallConn means a socket doesn't expose its descriptor.
+	ErrNotSyscallConn = errors.New("socket doesn't expose its descriptor")
+)
+
+// accept registers l with the poller of its reactor. On every event the
+// pending connections are accepted inline, without blocking, until the
+// backlog is empty, then the one-shot event is re-armed.
+func (s *Server) accept(l *listener) error {
+	sc, ok := l.ln.(syscall.Conn)
+	if !ok {
+		return ErrNotSyscallConn
+	}
+
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return err
+	}
+	l.rawLn = rc
+
+	spare, err := openSpare()
+	if err != nil {
+		return err
+	}
+
+	l.spareMu.Lock()
+	l.spare = spare
+	l.spareMu.Unlock()
+
+	desc, err := netpoll.HandleListener(l.ln, netpoll.EventRead|netpoll.EventOneShot)
+	if err != nil {
+		return err
+	}
+	l.acceptDesc = desc
+
+	return l.reactor.poller.Start(desc, func(e netpoll.Event) {
+		if e&netpoll.EventPollerClosed != 0 || l.isClosed() {
+			return
+		}
+
+		delay, stop := s.acceptBatch(l)
+		switch {
+		case stop:
+		case delay > 0:
+			time.AfterFunc(delay, func() {
+				if !l.isClosed() {
+					l.reactor.poller.Resume(desc)
+				}
+			})
+		default:
+			l.reactor.poller.Resume(desc)
+		}
+	})
+}
+
+// acceptBatch accepts up to acceptBatch connections. It returns how long
+// to back off when out of resources, and stop on a fatal error.
+func (s *Server) acceptBatch(l *listener) (delay time.Duration, stop bool) {
+	// netpoll reaches the descriptor through os.File.Fd, which switches the
+	// listener, sharing its file description, to blocking mode on every
+	// Resume.
+	l.rawLn.Control(func(fd uintptr) {
+		unix.SetNonblock(int(fd), true)
+	})
+
+	for i := 0; i < acceptBatch; i++ {
+		conn, err := acceptNonblock(l.rawLn)
+		if err == nil {
+			l.backoff = 0
+			s.handle(l, conn)
+			continue
+		}
+
+		switch {
+		case err == unix.EAGAIN:
+			return 0, false
+
+		case errors.Is(err, unix.EMFILE) || errors.Is(err, unix.ENFILE):
+			l.shed()
+			s.acceptError(l, err)
+			return l.nextBackoff(), false
+
+		case errors.Is(err, unix.ENOBUFS) || errors.Is(err, unix.ENOMEM):
+			s.acceptError(l, err)
+			return l.nextBackoff(), false
+
+		default:
+			if !l.isClosed() {
+				s.acceptError(l, err)
+			}
+			return 0, true
+		}
+	}
+
+	return 0, false
+}
+
+// handle serves a freshly accepted connection.
+func (s *Server) handle(l *listener, conn net.Conn) {
+	if err := s.handler.OnAccept(); err != nil {
+		s.reject(l, conn, err)
+		return
+	}
+
+	if err := l.socket.conn(conn, l.conf.network()); err != nil {
+		s.reject(l, conn, err)
+		return
+	}
+
+	switch {
+	case s.proxied(conn):
+		go s.proxy(l, conn)
+	case s.conf.TLSConfig != nil:
+		go s.handshake(l, conn, nil)
+	default:
+		s.start(l, conn, conn, nil)
+	}
+}
+
+// start serves a connection accepted by l. One that can't be polled is
+// rejected, and reported like a failed accept.
+func (s *Server) start(l *listener, raw, conn net.Conn, hdr *ProxyHeader) {
+	if _, err := s.serve(l.reactor, l, raw, conn, hdr); err != nil {
+		s.reject(l, raw, err)
+		s.acceptError(l, err)
+	}
+}
+
+// acceptNonblock accepts a pending connection without waiting, the error is
+// unix.EAGAIN when there is none.
+func acceptNonblock(rc syscall.RawConn) (net.Conn, error) {
+	var (
+		nfd int
+		sa  unix.Sockaddr
+		err error
+	)
+
+	// Listeners only support Control.
+	if cerr := rc.Control(func(fd uintptr) {
+		for {
+			nfd, sa, err = unix.Accept4(int(fd), unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC)
+			if err != unix.EINTR && err != unix.ECONNABORTED {
+				return
+			}
+		}
+	}); cerr != nil {
+		return nil, cerr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	f := os.NewFile(uintptr(nfd), "")
+	defer f.Close()
+
+	conn, err := net.FileConn(f)
+	if err != nil {
+		return nil, err
+	}
+
+	// The peer can't be read back from a connection reset in the backlog.
+	if tc, ok := conn.(*net.TCPConn); ok && tc.RemoteAddr() == nil {
+		return &resetConn{TCPConn: tc, remote: tcpAddr(sa)}, nil
+	}
+
+	return conn, nil
+}
+
+// resetConn is a connection whose peer address comes from accept.
+type resetConn struct {
+	*net.TCPConn
+	remote net.Addr
+}
+
+func (c *resetConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func tcpAddr(sa unix.Sockaddr) net.Addr {
+	switch sa := sa.(type) {
+	case *unix.SockaddrInet4:
+		return &net.TCPAddr{IP: append(net.IP(nil), sa.Addr[:]...), Port: sa.Port}
+	case *unix.SockaddrInet6:
+		addr := &net.TCPAddr{IP: append(net.IP(nil), sa.Addr[:]...), Port: sa.Port}
+		if sa.ZoneId != 0 {
+			if ifi, err := net.InterfaceByIndex(int(sa.ZoneId)); err == nil {
+				addr.Zone = ifi.Name
+			}
+		}
+		return addr
+	}
+
+	return &net.TCPAddr{}
+}
+
+// shed closes the spare descriptor to accept and drop the pending
+// connections, so that clients get refused instead of hanging in the
+// backlog while the process is out of descriptors.
+func (l *listener) shed() {
+	l.spareMu.Lock()
+	defer l.spareMu.Unlock()
+
+	if l.isClosed() {
+		return
+	}
+
+	// Another thread may have taken the descriptor freed last time, take
+	// it back for the next retry.
+	if l.spare < 0 {
+		l.spare, _ = openSpare()
+		return
+	}
+
+	unix.Close(l.spare)
+
+	l.rawLn.Control(func(fd uintptr) {
+		for i := 0; i < acceptBatch; i++ {
+			nfd, _, err := unix.Accept4(int(fd), unix.SOCK_CLOEXEC)
+			if err != nil {
+				return
+			}
+			unix.Close(nfd)
+		}
+	})
+
+	l.spare, _ = openSpare()
+}
+
+func (l *listener) nextBackoff() time.Duration {
+	l.backoff *= 2
+
+	if l.backoff < minAcceptBackoff {
+		l.backoff = minAcceptBackoff
+	}
+
+	if l.backoff > maxAcceptBackoff {
+		l.backoff = maxAcceptBackoff
+	}
+
+	return l.backoff
+}
+
+// openSpare reserves a descriptor for shed.
+func openSpare() (int, error) {
+	fd, err := unix.Open("/dev/null", unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return -1, os.NewSyscallError("open", err)
+	}
+
+	return fd, nil
+}
@@ -0,0 +1,201 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package tcp
+
+import (
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Size classes of read buffers, a connection moves up a class when a read
+// fills its buffer and down when reads use less than a quarter of it.
+var bufferClasses = [...]int{512, 2 << 10, 8 << 10, 32 << 10, 128 << 10}
+
+var bufferPools [len(bufferClasses)]sync.Pool
+
+// Buffer is a read buffer borrowed from the server. B holds the bytes read,
+// it must not be used after Release.
+type Buffer struct {
+	B        []byte
+	class    int
+	released bool
+}
+
+func getBuffer(class int) *Buffer {
+	if b, ok := bufferPools[class].Get().(*Buffer); ok {
+		b.B = b.B[:cap(b.B)]
+		b.released = false
+		return b
+	}
+
+	return &Buffer{
+		B:     make([]byte, bufferClasses[class]),
+		class: class,
+	}
+}
+
+// Release gives the buffer back to the server, releasing it again does
+// nothing.
+func (b *Buffer) Release() {
+	if b.released {
+		return
+	}
+
+	b.released = true
+	b.B = b.B[:0]
+	bufferPools[b.class].Put(b)
+}
+
+// readBuffers reads into pooled buffers and hands each to h until the
+// socket is drained. TLS connections get one read per call.
+func (c *Conn) readBuffers(h BufferHandler) error {
+	for {
+		if err := c.admit(); err != nil {
+			return err
+		}
+
+		b := getBuffer(c.readClass)
+
+		n, nonblock, err := c.readNonblock(b.B)
+		if n > 0 {
+			c.received(n)
+			c.adapt(n, len(b.B))
+
+			b.B = b.B[:n]
+			if herr := h.OnReadBuffer(c, b); herr != nil {
+				return herr
+			}
+		} else {
+			b.Release()
+		}
+
+		switch {
+		case err == unix.EAGAIN:
+			return nil
+		case err != nil:
+			return err
+		case n == 0:
+			return io.EOF
+		case !nonblock || n < bufferClasses[c.readClass]:
+			return nil
+		}
+	}
+}
+
+// readNonblock reads what's in the socket without waiting, nonblock is
+// false for TLS connections, which are read through the TLS layer.
+func (c *Conn) readNonblock(b []byte) (n int, nonblock bool, err error) {
+	if c.tls != nil || c.raw == nil {
+		n, err = c.read(b)
+		if err == nil && n == 0 {
+			err = unix.EAGAIN
+		}
+
+		return n, false, err
+	}
+
+	if rerr := c.raw.Read(func(fd uintptr) bool {
+		for {
+			n, err = unix.Read(int(fd), b)
+			if err != unix.EINTR {
+				return true
+			}
+		}
+	}); rerr != nil {
+		return 0, true, rerr
+	}
+
+	if n < 0 {
+		n = 0
+	}
+
+	return n, true, err
+}
+
+func (c *Conn) adapt(n, size int) {
+	switch {
+	case n == size && c.readClass < len(bufferClasses)-1:
+		c.readClass++
+	case n < size/4 && c.readClass > 0:
+		c.readClass--
+	}
+}
+
+// Readv reads into bufs in order with a single readv(2), blocking until
+// data is available. TLS connections only fill the first buffer.
+func (c *Conn) Readv(bufs [][]byte) (n int, err error) {
+	if c.tls != nil || c.raw == nil {
+		for _, b := range bufs {
+			if len(b) > 0 {
+				return c.Read(b)
+			}
+		}
+
+		return 0, nil
+	}
+
+	if rerr := c.raw.Read(func(fd uintptr) bool {
+		n, err = unix.Readv(int(fd), bufs)
+		return err != unix.EAGAIN
+	}); rerr != nil {
+		return 0, rerr
+	}
+
+	if n < 0 {
+		n = 0
+	}
+
+	if n > 0 {
+		c.received(n)
+	} else if err == nil {
+		err = io.EOF
+	}
+
+	return n, err
+}
+
+// Writev writes bufs with a single writev(2) where possible. bufs itself is
+// left untouched.
+func (c *Conn) Writev(bufs [][]byte) (int64, error) {
+	v := make(net.Buffers, len(bufs))
+	copy(v, bufs)
+
+	n, err := v.WriteTo(c.Conn)
+	if n > 0 {
+		atomic.StoreInt64(&c.lastWrite, time.Now().UnixNano())
+	}
+
+	return n, err
+}
@@ -0,0 +1,458 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package tcp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/fengyfei/nuts/scheduler"
+	"github.com/mailru/easygo/netpoll"
+)
+
+const (
+	defaultDialTimeout = 5 * time.Second
+	defaultMinBackoff  = 100 * time.Millisecond
+	defaultMaxBackoff  = 30 * time.Second
+)
+
+var (
+	// ErrClientClosed means the client was closed.
+	ErrClientClosed = errors.New("client closed")
+)
+
+// Client dials TCP connections and serves them exactly like a Server serves
+// accepted ones: reads are polled and dispatched to the Handler on the pool.
+type Client struct {
+	conf   *ClientConfig
+	engine *Server
+	done   chan struct{}
+
+	mu     sync.Mutex
+	pools  map[string]*connPool
+	closed bool
+
+	// Connections open or being dialed, the poller stops along with the
+	// last one once the client is closed.
+	open int
+}
+
+// connPool keeps a fixed number of connections to an address open.
+type connPool struct {
+	address string
+
+	mu      sync.Mutex
+	conns   []*Conn
+	next    int
+	pending int
+	fails   int
+	retryAt time.Time
+	lastErr error
+}
+
+// NewClient creates a client, h handles the events of every connection.
+func NewClient(c *ClientConfig, h Handler, pool *scheduler.Pool) (*Client, error) {
+	p, err := netpoll.New(nil)
+	if err != nil {
+		return nil, ErrEpollCreate
+	}
+
+	cl := &Client{
+		conf:  c,
+		done:  make(chan struct{}),
+		pools: make(map[string]*connPool),
+	}
+
+	cl.engine = &Server{
+		conf: &Config{},
+		reactors: []*reactor{
+			{
+				poller:    p,
+				scheduler: pool,
+			},
+		},
+		handler:  h,
+		released: cl.released,
+	}
+
+	if c.HealthCheck != nil && c.HealthCheckInterval > 0 {
+		go cl.check()
+	}
+
+	return cl, nil
+}
+
+// Dial opens a connection which is not pooled, nor reconnected.
+func (cl *Client) Dial(address string) (*Conn, error) {
+	return cl.DialContext(context.Background(), address)
+}
+
+// DialContext is Dial, giving up once ctx is done or the dial timeout
+// expires, whichever comes first.
+func (cl *Client) DialContext(ctx context.Context, address string) (*Conn, error) {
+	if err := cl.hold(); err != nil {
+		return nil, err
+	}
+
+	d := net.Dialer{Timeout: cl.conf.dialTimeout()}
+	raw, err := d.DialContext(ctx, cl.conf.network(), address)
+	if err != nil {
+		cl.drop()
+		return nil, err
+	}
+
+	conn := raw
+	if cl.conf.TLSConfig != nil {
+		config := cl.conf.TLSConfig
+		if config.ServerName == "" {
+			config = config.Clone()
+			config.ServerName, _, _ = net.SplitHostPort(address)
+		}
+
+		tc := tls.Client(raw, config)
+
+		raw.SetDeadline(time.Now().Add(cl.conf.dialTimeout()))
+		if err = tc.HandshakeContext(ctx); err != nil {
+			raw.Close()
+			cl.drop()
+			return nil, err
+		}
+		raw.SetDeadline(time.Time{})
+
+		conn = tc
+	}
+
+	c, err := cl.engine.serve(cl.engine.reactors[0], nil, raw, conn, nil)
+	if err != nil {
+		raw.Close()
+		cl.drop()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Get returns one of the pooled connections to address, in turn. The pool
+// is filled on first use; while reconnecting after failures, Get fails fast
+// with the last dial error.
+func (cl *Client) Get(address string) (*Conn, error) {
+	return cl.GetContext(context.Background(), address)
+}
+
+// GetContext is Get, ctx bounds the dial of an empty pool.
+func (cl *Client) GetContext(ctx context.Context, address string) (*Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p, err := cl.pool(address)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// A connection may close before it's added, released misses it then.
+	for i := len(p.conns) - 1; i >= 0; i-- {
+		if p.conns[i].isClosed() {
+			p.remove(p.conns[i])
+		}
+	}
+
+	if len(p.conns) == 0 {
+		if time.Now().Before(p.retryAt) {
+			return nil, p.lastErr
+		}
+
+		if err = cl.dial(ctx, p); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(p.conns)+p.pending < cl.conf.poolSize() {
+		go cl.fill(p)
+	}
+
+	p.next = (p.next + 1) % len(p.conns)
+	return p.conns[p.next], nil
+}
+
+// dial adds a connection to an empty pool for Get, p.mu is held. It's
+// released while dialing with a slot reserved, as fill does, since closing
+// a connection takes it.
+func (cl *Client) dial(ctx context.Context, p *connPool) error {
+	p.pending++
+	p.mu.Unlock()
+
+	c, err := cl.DialContext(ctx, p.address)
+
+	p.mu.Lock()
+	p.pending--
+
+	if err != nil {
+		// The caller gave up, the address didn't fail.
+		if ctx.Err() == nil {
+			p.failed(cl.conf, err)
+		}
+		return err
+	}
+
+	switch {
+	case cl.isClosed():
+		p.mu.Unlock()
+		c.Close()
+		p.mu.Lock()
+		return ErrClientClosed
+	case c.isClosed():
+		// By the Handler's OnOpen.
+		return net.ErrClosed
+	}
+
+	p.add(c)
+	return nil
+}
+
+// Close closes every pooled connection and stops reconnecting. Connections
+// returned by Dial are left open, the poller serving them stops once they
+// are all closed. Dial and Get fail with ErrClientClosed afterwards.
+func (cl *Client) Close() error {
+	cl.mu.Lock()
+	if cl.closed {
+		cl.mu.Unlock()
+		return nil
+	}
+
+	// Held until the pooled connections are closed, so that the poller
+	// stops once, on the last drop.
+	cl.closed = true
+	cl.open++
+	close(cl.done)
+
+	pools := cl.pools
+	cl.pools = nil
+	cl.mu.Unlock()
+
+	for _, p := range pools {
+		p.mu.Lock()
+		conns := p.conns
+		p.conns = nil
+		p.mu.Unlock()
+
+		for _, c := range conns {
+			c.Close()
+		}
+	}
+
+	cl.drop()
+	return nil
+}
+
+// hold counts a connection about to be dialed.
+func (cl *Client) hold() error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if cl.closed {
+		return ErrClientClosed
+	}
+
+	cl.open++
+	return nil
+}
+
+// drop undoes hold, once the connection failed or closed. The last drop
+// after Close stops the poller, on another goroutine since connections may
+// close from its callbacks.
+func (cl *Client) drop() {
+	cl.mu.Lock()
+	cl.open--
+	last := cl.closed && cl.open == 0
+	cl.mu.Unlock()
+
+	if last {
+		go cl.engine.reactors[0].close()
+	}
+}
+
+func (cl *Client) pool(address string) (*connPool, error) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if cl.closed {
+		return nil, ErrClientClosed
+	}
+
+	p, ok := cl.pools[address]
+	if !ok {
+		p = &connPool{
+			address: address,
+		}
+		cl.pools[address] = p
+	}
+
+	return p, nil
+}
+
+// fill dials until the pool is full, backing off on failures.
+func (cl *Client) fill(p *connPool) {
+	for {
+		p.mu.Lock()
+		if cl.isClosed() || len(p.conns)+p.pending >= cl.conf.poolSize() || time.Now().Before(p.retryAt) {
+			p.mu.Unlock()
+			return
+		}
+		p.pending++
+		p.mu.Unlock()
+
+		c, err := cl.Dial(p.address)
+
+		p.mu.Lock()
+		p.pending--
+		if err != nil {
+			delay := p.failed(cl.conf, err)
+			p.mu.Unlock()
+
+			time.AfterFunc(delay, func() {
+				cl.fill(p)
+			})
+			return
+		}
+
+		if cl.isClosed() {
+			p.mu.Unlock()
+			c.Close()
+			return
+		}
+
+		p.add(c)
+		p.mu.Unlock()
+	}
+}
+
+// released removes a closed connection from its pool and replaces it.
+func (cl *Client) released(c *Conn) {
+	defer cl.drop()
+
+	cl.mu.Lock()
+	pools := make([]*connPool, 0, len(cl.pools))
+	for _, p := range cl.pools {
+		pools = append(pools, p)
+	}
+	cl.mu.Unlock()
+
+	for _, p := range pools {
+		p.mu.Lock()
+		found := p.remove(c)
+		p.mu.Unlock()
+
+		if found {
+			go cl.fill(p)
+			return
+		}
+	}
+}
+
+// check runs the health check on every pooled connection periodically.
+func (cl *Client) check() {
+	ticker := time.NewTicker(cl.conf.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-cl.done:
+			return
+		}
+
+		cl.mu.Lock()
+		var conns []*Conn
+		for _, p := range cl.pools {
+			p.mu.Lock()
+			conns = append(conns, p.conns...)
+			p.mu.Unlock()
+		}
+		cl.mu.Unlock()
+
+		for _, c := range conns {
+			c := c
+			c.reactor.scheduler.Schedule(scheduler.TaskFunc(func() error {
+				if err := cl.conf.HealthCheck(c); err != nil {
+					c.Close()
+					return err
+				}
+				return nil
+			}))
+		}
+	}
+}
+
+func (cl *Client) isClosed() bool {
+	select {
+	case <-cl.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// add must be called with p.mu held.
+func (p *connPool) add(c *Conn) {
+	p.conns = append(p.conns, c)
+	p.fails = 0
+	p.retryAt = time.Time{}
+}
+
+// remove must be called with p.mu held.
+func (p *connPool) remove(c *Conn) bool {
+	for i, pc := range p.conns {
+		if pc == c {
+			p.conns = append(p.conns[:i], p.conns[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// failed records a dial error and returns the backoff, p.mu must be held.
+func (p *connPool) failed(conf *ClientConfig, err error) time.Duration {
+	p.fails++
+	p.lastErr = err
+
+	delay := conf.backoff(p.fails)
+	p.retryAt = time.Now().Add(delay)
+
+	return delay
+}
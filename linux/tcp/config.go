@@ -29,7 +29,246 @@
 
 package tcp
 
+import (
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/fengyfei/nuts/scheduler"
+)
+
 // Config is configuration for a TCP server.
 type Config struct {
+	// Network is one of "tcp", "tcp4", "tcp6" or "unix", "tcp" by default.
+	// A unix Address starting with '@' is in the abstract namespace.
+	Network string
 	Address string
+
+	// Listeners replace Network and Address when not empty, the server
+	// accepts on all of them. More can be added with Server.Listen.
+	Listeners []Listener
+
+	// Reactors is the number of pollers. Each TCP listener is bound once
+	// per reactor with SO_REUSEPORT. Zero means one, without SO_REUSEPORT.
+	Reactors int
+
+	// Pools partitions the reactors, reactor i runs on Pools[i%len(Pools)].
+	// Empty means all reactors share the pool given to StartServer.
+	Pools []*scheduler.Pool
+
+	// Socket tunes the listener and accepted connections.
+	Socket SocketOptions
+
+	// Idle detection, zero disables the corresponding timeout.
+	ReadIdleTimeout  time.Duration
+	WriteIdleTimeout time.Duration
+	MaxLifetime      time.Duration
+
+	// IdleTick is the resolution of idle detection, by default derived
+	// from the smallest timeout above.
+	IdleTick time.Duration
+
+	// TLSConfig enables TLS termination when not nil. Use a CertStore as
+	// GetCertificate for SNI and hot-reload, and ClientAuth/ClientCAs for
+	// client certificates.
+	TLSConfig *tls.Config
+
+	// HandshakeTimeout bounds the TLS handshake, 10 seconds by default.
+	HandshakeTimeout time.Duration
+
+	// Proxy enables the PROXY protocol when not nil, the client address
+	// it carries is the RemoteAddr of the Conn.
+	Proxy *ProxyConfig
+
+	// RateLimit caps what is read from connections when not nil.
+	RateLimit *RateLimit
+
+	// PublishTimeout bounds each write of Server.Publish, 1 second by
+	// default. A connection that doesn't keep up is closed.
+	PublishTimeout time.Duration
+
+	// OnServerError is told about errors that aren't tied to a connection,
+	// such as failing to accept. Running out of descriptors or memory only
+	// pauses accepting, any other accept error stops it.
+	OnServerError func(error)
+
+	// Logger is told about the events below when not nil.
+	Logger Logger
+
+	// Lifecycle hooks, each optional. They run on the goroutine that saw
+	// the event, so they must not block.
+	//
+	// OnConnReject is called when Handler.OnAccept, the socket options,
+	// the PROXY header or the TLS handshake fail, the connection is closed
+	// and never reaches the Handler. OnReadError gets the error returned
+	// by the Handler, before OnError. OnPollerError gets the errors of
+	// epoll_wait. OnShutdown is called once, when the server is closed.
+	OnListen      func(net.Addr)
+	OnConnAccept  func(*Conn)
+	OnConnReject  func(remote net.Addr, err error)
+	OnReadError   func(c *Conn, err error)
+	OnPollerError func(error)
+	OnShutdown    func()
+}
+
+// ClientConfig is configuration for a TCP client.
+type ClientConfig struct {
+	// Network is dialed as in Config, "tcp" by default.
+	Network string
+
+	// PoolSize is the number of connections kept open per address, 1 by
+	// default.
+	PoolSize int
+
+	// DialTimeout bounds connecting, including the TLS handshake, 5 seconds
+	// by default.
+	DialTimeout time.Duration
+
+	// Reconnecting waits MinBackoff after the first failure, doubling up to
+	// MaxBackoff. Defaults are 100 milliseconds and 30 seconds.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// HealthCheck is run on every pooled connection each
+	// HealthCheckInterval, a connection failing it is closed and replaced.
+	HealthCheck         func(*Conn) error
+	HealthCheckInterval time.Duration
+
+	// TLSConfig enables TLS when not nil. ServerName defaults to the host
+	// being dialed.
+	TLSConfig *tls.Config
+}
+
+func (c *Config) validate() error {
+	names := make(map[string]bool)
+	local := true
+
+	for _, l := range c.listeners() {
+		if err := l.validate(c.Reactors, &c.Socket); err != nil {
+			return err
+		}
+
+		if names[l.Name] {
+			return ErrListenerExists
+		}
+		names[l.Name] = true
+
+		local = local && l.network() == "unix"
+	}
+
+	if c.Proxy != nil {
+		if len(c.Proxy.Trusted) == 0 && !local {
+			return ErrNoTrustedProxy
+		}
+
+		if _, err := c.Proxy.networks(); err != nil {
+			return err
+		}
+	}
+
+	if c.RateLimit != nil {
+		if err := c.RateLimit.validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// listeners returns copies of the listeners to start with.
+func (c *Config) listeners() []Listener {
+	if len(c.Listeners) == 0 {
+		return []Listener{{
+			Network: c.Network,
+			Address: c.Address,
+		}}
+	}
+
+	return append([]Listener(nil), c.Listeners...)
+}
+
+func (c *Config) idleEnabled() bool {
+	return c.ReadIdleTimeout > 0 || c.WriteIdleTimeout > 0 || c.MaxLifetime > 0
+}
+
+func (c *Config) idleTick() time.Duration {
+	if c.IdleTick > 0 {
+		return c.IdleTick
+	}
+
+	tick := time.Second
+	for _, d := range []time.Duration{c.ReadIdleTimeout, c.WriteIdleTimeout, c.MaxLifetime} {
+		if d > 0 && d/4 < tick {
+			tick = d / 4
+		}
+	}
+
+	if tick < 10*time.Millisecond {
+		tick = 10 * time.Millisecond
+	}
+
+	return tick
+}
+
+func (c *Config) handshakeTimeout() time.Duration {
+	if c.HandshakeTimeout > 0 {
+		return c.HandshakeTimeout
+	}
+
+	return defaultHandshakeTimeout
+}
+
+func (c *Config) publishTimeout() time.Duration {
+	if c.PublishTimeout > 0 {
+		return c.PublishTimeout
+	}
+
+	return defaultPublishTimeout
+}
+
+func (c *ClientConfig) network() string {
+	if c.Network == "" {
+		return "tcp"
+	}
+
+	return c.Network
+}
+
+func (c *ClientConfig) poolSize() int {
+	if c.PoolSize > 0 {
+		return c.PoolSize
+	}
+
+	return 1
+}
+
+func (c *ClientConfig) dialTimeout() time.Duration {
+	if c.DialTimeout > 0 {
+		return c.DialTimeout
+	}
+
+	return defaultDialTimeout
+}
+
+// backoff returns the delay before the next dial after fails failures.
+func (c *ClientConfig) backoff(fails int) time.Duration {
+	min, max := c.MinBackoff, c.MaxBackoff
+	if min <= 0 {
+		min = defaultMinBackoff
+	}
+
+	if max <= 0 {
+		max = defaultMaxBackoff
+	}
+
+	d := min
+	for i := 1; i < fails && d < max; i++ {
+		d *= 2
+	}
+
+	if d > max {
+		d = max
+	}
+
+	return d
 }
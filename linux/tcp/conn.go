@@ -0,0 +1,400 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package tcp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/mailru/easygo/netpoll"
+	"golang.org/x/sys/unix"
+)
+
+// Conn is a connection accepted by a Server. It is the net.Conn passed to
+// every Handler method, so handlers may type assert to reach it.
+type Conn struct {
+	// Unix nano timestamps, accessed atomically.
+	lastRead      int64
+	lastWrite     int64
+	readNotified  int64
+	writeNotified int64
+
+	net.Conn
+	raw      syscall.RawConn
+	server   *Server
+	reactor  *reactor
+	listener *listener
+	desc     *netpoll.Desc
+	created  time.Time
+	closed   int32
+
+	// Held while desc is registered with the poller and when it is closed.
+	pollMu sync.Mutex
+
+	// Set while a read task is scheduled or running, accessed atomically.
+	reading int32
+
+	// Buffer size class, only used by the read task.
+	readClass int
+
+	// TLS connections only, see buffered.
+	tls    *tls.Conn
+	rmu    sync.Mutex
+	peek   [1]byte
+	peeked bool
+
+	// Deadlines set through the Conn, restored after the server's own.
+	rdmu          sync.Mutex
+	readDeadline  time.Time
+	wdmu          sync.Mutex
+	writeDeadline time.Time
+
+	// Budgets of the connection, nil when unlimited.
+	limits *limiter
+
+	// Topics joined, guarded by the server's groups.
+	topics map[string]struct{}
+
+	// Header of connections from a trusted proxy.
+	proxy *ProxyHeader
+
+	// Timing wheel position, guarded by the wheel's mutex.
+	slot   int
+	rounds int
+}
+
+func newConn(s *Server, r *reactor, l *listener, raw, conn net.Conn, desc *netpoll.Desc) *Conn {
+	var rc syscall.RawConn
+
+	now := time.Now()
+	tc, _ := conn.(*tls.Conn)
+
+	if sc, ok := raw.(syscall.Conn); ok {
+		rc, _ = sc.SyscallConn()
+	}
+
+	var limits *limiter
+	if l := s.conf.RateLimit; l != nil {
+		limits = newLimiter(l.BytesPerSecond, l.MessagesPerSecond)
+	}
+
+	return &Conn{
+		lastRead:  now.UnixNano(),
+		lastWrite: now.UnixNano(),
+		Conn:      conn,
+		raw:       rc,
+		server:    s,
+		reactor:   r,
+		listener:  l,
+		desc:      desc,
+		created:   now,
+		slot:      -1,
+		tls:       tc,
+		limits:    limits,
+	}
+}
+
+// Read reads data from the connection and records the activity.
+func (c *Conn) Read(b []byte) (n int, err error) {
+	n, err = c.read(b)
+	if n > 0 {
+		c.received(n)
+	}
+
+	return n, err
+}
+
+func (c *Conn) read(b []byte) (n int, err error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	c.rmu.Lock()
+	if c.peeked {
+		b[0] = c.peek[0]
+		c.peeked = false
+		n = 1
+
+		if len(b) > 1 {
+			m, _ := c.readBuffered(b[1:])
+			n += m
+		}
+	} else {
+		n, err = c.Conn.Read(b)
+	}
+	c.rmu.Unlock()
+
+	return n, err
+}
+
+// Write writes data to the connection and records the activity.
+func (c *Conn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		atomic.StoreInt64(&c.lastWrite, time.Now().UnixNano())
+	}
+
+	return n, err
+}
+
+// SetDeadline sets the read and write deadlines of the connection.
+func (c *Conn) SetDeadline(t time.Time) error {
+	c.rdmu.Lock()
+	defer c.rdmu.Unlock()
+
+	c.wdmu.Lock()
+	defer c.wdmu.Unlock()
+
+	c.readDeadline, c.writeDeadline = t, t
+	return c.Conn.SetDeadline(t)
+}
+
+// SetReadDeadline sets the read deadline of the connection.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.rdmu.Lock()
+	defer c.rdmu.Unlock()
+
+	c.readDeadline = t
+	return c.Conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the write deadline of the connection.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	c.wdmu.Lock()
+	defer c.wdmu.Unlock()
+
+	c.writeDeadline = t
+	return c.Conn.SetWriteDeadline(t)
+}
+
+// Close unregisters the connection from the server and closes it. The
+// Handler's OnClose is called exactly once, whichever side closes first.
+func (c *Conn) Close() error {
+	if !atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		return nil
+	}
+
+	err := c.Conn.Close()
+	c.server.release(c)
+
+	return err
+}
+
+// TLS returns the state of a TLS connection, nil for plain connections.
+func (c *Conn) TLS() *tls.ConnectionState {
+	if c.tls == nil {
+		return nil
+	}
+
+	state := c.tls.ConnectionState()
+	return &state
+}
+
+// PeerCertificate returns the verified client certificate, or nil if the
+// connection is not TLS or the client didn't present one that could be
+// verified. Under tls.RequestClientCert or RequireAnyClientCert, TLS gives
+// the certificates presented but not verified.
+func (c *Conn) PeerCertificate() *x509.Certificate {
+	if c.tls == nil {
+		return nil
+	}
+
+	chains := c.tls.ConnectionState().VerifiedChains
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return nil
+	}
+
+	return chains[0][0]
+}
+
+// RemoteAddr returns the address of the client, the one given by the PROXY
+// header when there is one.
+func (c *Conn) RemoteAddr() net.Addr {
+	if c.proxy != nil && c.proxy.Source != nil {
+		return c.proxy.Source
+	}
+
+	return c.Conn.RemoteAddr()
+}
+
+// Listener returns the listener that accepted the connection, the zero
+// Listener for client connections.
+func (c *Conn) Listener() Listener {
+	if c.listener == nil {
+		return Listener{}
+	}
+
+	return *c.listener.conf
+}
+
+// Proxy returns the PROXY header the connection started with, nil if it
+// didn't come from a trusted proxy.
+func (c *Conn) Proxy() *ProxyHeader {
+	return c.proxy
+}
+
+// Results of probe.
+const (
+	probeEmpty  = iota // A Read would block
+	probeData          // Data is waiting, in the socket or decrypted by TLS
+	probeClosed        // Only EOF or an error is left
+)
+
+// probe tells what a Read would get, without consuming anything.
+func (c *Conn) probe() int {
+	if c.buffered() || c.raw == nil {
+		return probeData
+	}
+
+	// A closed descriptor can't even be probed.
+	state := probeClosed
+	c.raw.Control(func(fd uintptr) {
+		var b [1]byte
+
+		n, _, err := unix.Recvfrom(int(fd), b[:], unix.MSG_PEEK|unix.MSG_DONTWAIT)
+		for err == unix.EINTR {
+			n, _, err = unix.Recvfrom(int(fd), b[:], unix.MSG_PEEK|unix.MSG_DONTWAIT)
+		}
+
+		switch {
+		case err == unix.EAGAIN:
+			state = probeEmpty
+		case err == nil && n > 0:
+			state = probeData
+		}
+	})
+
+	return state
+}
+
+// Credentials identify the process at the other end of a Unix socket.
+type Credentials struct {
+	PID int32
+	UID uint32
+	GID uint32
+}
+
+// PeerCredentials returns the credentials the peer had when it connected,
+// as reported by SO_PEERCRED. It fails with ErrNetwork on TCP connections.
+func (c *Conn) PeerCredentials() (*Credentials, error) {
+	if c.raw == nil || c.Conn.LocalAddr().Network() != "unix" {
+		return nil, ErrNetwork
+	}
+
+	var (
+		cred *unix.Ucred
+		err  error
+	)
+
+	if cerr := c.raw.Control(func(fd uintptr) {
+		cred, err = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	}); cerr != nil {
+		return nil, cerr
+	}
+
+	if err != nil {
+		return nil, os.NewSyscallError("getsockopt", err)
+	}
+
+	return &Credentials{
+		PID: cred.Pid,
+		UID: cred.Uid,
+		GID: cred.Gid,
+	}, nil
+}
+
+// buffered reports whether the TLS layer holds data already pulled off the
+// socket. Such data never raises another epoll event, so the server has to
+// keep reading. It's always false for plain connections.
+func (c *Conn) buffered() bool {
+	if c.tls == nil || c.isClosed() {
+		return false
+	}
+
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+
+	if !c.peeked {
+		n, _ := c.readBuffered(c.peek[:])
+		c.peeked = n > 0
+	}
+
+	return c.peeked
+}
+
+// readBuffered reads from the TLS layer without waiting on the socket,
+// the read deadline set through the Conn is restored afterwards.
+func (c *Conn) readBuffered(b []byte) (int, error) {
+	c.rdmu.Lock()
+	defer c.rdmu.Unlock()
+
+	c.tls.SetReadDeadline(time.Now())
+	defer c.tls.SetReadDeadline(c.readDeadline)
+
+	return c.tls.Read(b)
+}
+
+// stopPolling stops watching an open connection, once closed its
+// descriptor is released along with it.
+func (c *Conn) stopPolling() {
+	c.pollMu.Lock()
+	if !c.isClosed() {
+		c.reactor.poller.Stop(c.desc)
+	}
+	c.pollMu.Unlock()
+}
+
+// resumePolling watches the connection again after stopPolling, it fails
+// with net.ErrClosed once the connection is closed.
+func (c *Conn) resumePolling(onEvent func(netpoll.Event)) error {
+	c.pollMu.Lock()
+	defer c.pollMu.Unlock()
+
+	if c.isClosed() {
+		return net.ErrClosed
+	}
+
+	return c.reactor.poller.Start(c.desc, onEvent)
+}
+
+// Created returns the time the connection was accepted.
+func (c *Conn) Created() time.Time {
+	return c.created
+}
+
+func (c *Conn) isClosed() bool {
+	return atomic.LoadInt32(&c.closed) != 0
+}
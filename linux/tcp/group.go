@@ -0,0 +1,201 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package tcp
+
+import (
+	"sync"
+	"time"
+
+	"github.com/fengyfei/nuts/scheduler"
+)
+
+const (
+	// publishBatch is the number of connections a fan-out task writes to.
+	publishBatch = 64
+
+	defaultPublishTimeout = time.Second
+)
+
+// groups maps topics to the connections that joined them, its zero value
+// is ready to use.
+type groups struct {
+	mu     sync.RWMutex
+	topics map[string]map[*Conn]struct{}
+}
+
+// Join subscribes the connection to topic, it leaves all its topics when
+// closed.
+func (c *Conn) Join(topic string) {
+	g := &c.server.groups
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if c.isClosed() {
+		return
+	}
+
+	if g.topics == nil {
+		g.topics = make(map[string]map[*Conn]struct{})
+	}
+
+	members, ok := g.topics[topic]
+	if !ok {
+		members = make(map[*Conn]struct{})
+		g.topics[topic] = members
+	}
+	members[c] = struct{}{}
+
+	if c.topics == nil {
+		c.topics = make(map[string]struct{})
+	}
+	c.topics[topic] = struct{}{}
+}
+
+// Leave unsubscribes the connection from topic.
+func (c *Conn) Leave(topic string) {
+	g := &c.server.groups
+
+	g.mu.Lock()
+	g.remove(c, topic)
+	g.mu.Unlock()
+}
+
+// Topics returns the topics the connection joined.
+func (c *Conn) Topics() []string {
+	g := &c.server.groups
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	topics := make([]string, 0, len(c.topics))
+	for t := range c.topics {
+		topics = append(topics, t)
+	}
+
+	return topics
+}
+
+// Members returns the number of connections subscribed to topic.
+func (s *Server) Members(topic string) int {
+	s.groups.mu.RLock()
+	defer s.groups.mu.RUnlock()
+
+	return len(s.groups.topics[topic])
+}
+
+// Publish writes msg to every connection subscribed to topic and returns
+// their number. Writes are done by tasks on the reactors' pools, each
+// covering a batch of connections, so Publish doesn't wait for them; msg
+// must not be modified afterwards. A connection failing the write, or not
+// done within Config.PublishTimeout, is closed. When a pool's queue is
+// full, the batch gets a goroutine of its own, so that handlers can
+// publish without waiting on their own pool.
+func (s *Server) Publish(topic string, msg []byte) int {
+	s.groups.mu.RLock()
+	members := make([]*Conn, 0, len(s.groups.topics[topic]))
+	for c := range s.groups.topics[topic] {
+		members = append(members, c)
+	}
+	s.groups.mu.RUnlock()
+
+	timeout := s.conf.publishTimeout()
+
+	for i := 0; i < len(members); i += publishBatch {
+		end := i + publishBatch
+		if end > len(members) {
+			end = len(members)
+		}
+
+		batch := members[i:end]
+		r := s.reactors[(i/publishBatch)%len(s.reactors)]
+
+		task := scheduler.TaskFunc(func() error {
+			for _, c := range batch {
+				if !c.isClosed() {
+					c.publish(msg, timeout)
+				}
+			}
+
+			return nil
+		})
+
+		if !r.scheduler.TrySchedule(task) {
+			go task.Do()
+		}
+	}
+
+	return len(members)
+}
+
+// publish writes msg within timeout, or before the write deadline set
+// through the Conn if it's earlier, and closes the connection on failure.
+func (c *Conn) publish(msg []byte, timeout time.Duration) {
+	c.wdmu.Lock()
+
+	deadline := time.Now().Add(timeout)
+	if !c.writeDeadline.IsZero() && c.writeDeadline.Before(deadline) {
+		deadline = c.writeDeadline
+	}
+
+	c.Conn.SetWriteDeadline(deadline)
+	_, err := c.Write(msg)
+	c.Conn.SetWriteDeadline(c.writeDeadline)
+
+	c.wdmu.Unlock()
+
+	if err != nil {
+		c.Close()
+	}
+}
+
+// leaveAll unsubscribes a closed connection from all its topics.
+func (g *groups) leaveAll(c *Conn) {
+	g.mu.Lock()
+	for topic := range c.topics {
+		g.remove(c, topic)
+	}
+	g.mu.Unlock()
+}
+
+// remove needs mu held.
+func (g *groups) remove(c *Conn, topic string) {
+	members, ok := g.topics[topic]
+	if !ok {
+		return
+	}
+
+	delete(members, c)
+	if len(members) == 0 {
+		delete(g.topics, topic)
+	}
+
+	delete(c.topics, topic)
+}
@@ -40,3 +40,39 @@ type Handler interface {
 	OnError(net.Conn)
 	OnReadMessage(net.Conn) error
 }
+
+// OpenHandler is implemented by handlers that want to be told about new
+// connections. OnOpen is called once, before any other callback of the
+// connection, for accepted and dialed ones alike.
+type OpenHandler interface {
+	OnOpen(net.Conn)
+}
+
+// IdleState tells which timeout fired on a connection.
+type IdleState uint8
+
+const (
+	// ReadIdle means nothing was read within Config.ReadIdleTimeout.
+	ReadIdle IdleState = iota + 1
+	// WriteIdle means nothing was written within Config.WriteIdleTimeout.
+	WriteIdle
+	// LifetimeExpired means the connection outlived Config.MaxLifetime, it
+	// is closed right after OnIdle returns.
+	LifetimeExpired
+)
+
+// IdleHandler is implemented by handlers that want to be told about idle
+// connections, e.g. to send a heartbeat or close the connection.
+// Without it, the server closes connections on ReadIdle and LifetimeExpired.
+type IdleHandler interface {
+	OnIdle(net.Conn, IdleState)
+}
+
+// BufferHandler is implemented by handlers that let the server read. The
+// server drains the socket into pooled buffers and passes each one to
+// OnReadBuffer instead of calling OnReadMessage. The handler owns the
+// Buffer, and must Release it once done, possibly after OnReadBuffer
+// returned.
+type BufferHandler interface {
+	OnReadBuffer(net.Conn, *Buffer) error
+}
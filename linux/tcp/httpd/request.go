@@ -0,0 +1,160 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package httpd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// Longest chunk size line accepted, extensions included.
+const maxChunkLine = 4096
+
+var (
+	errIncomplete     = errors.New("incomplete request")
+	errHeaderTooLarge = errors.New("request header too large")
+	errBodyTooLarge   = errors.New("request body too large")
+	errBadRequest     = errors.New("malformed request")
+
+	crlf = []byte("\r\n")
+)
+
+// readHeader parses the request line and header at the start of b, it
+// returns the size they take.
+func readHeader(b []byte, limit int) (*http.Request, int, error) {
+	end := bytes.Index(b, []byte("\r\n\r\n"))
+	if end < 0 {
+		if len(b) > limit {
+			return nil, 0, errHeaderTooLarge
+		}
+
+		return nil, 0, errIncomplete
+	}
+
+	end += 4
+	if end > limit {
+		return nil, 0, errHeaderTooLarge
+	}
+
+	r, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(b[:end])))
+	if err != nil {
+		return nil, 0, errBadRequest
+	}
+
+	return r, end, nil
+}
+
+// readBody extracts the body of r from b, it returns the size it takes on
+// the wire.
+func readBody(r *http.Request, b []byte, limit int64) ([]byte, int, error) {
+	if len(r.TransferEncoding) > 0 {
+		return readChunked(b, limit)
+	}
+
+	switch n := r.ContentLength; {
+	case n <= 0:
+		return nil, 0, nil
+	case n > limit:
+		return nil, 0, errBodyTooLarge
+	case int64(len(b)) < n:
+		return nil, 0, errIncomplete
+	}
+
+	body := make([]byte, r.ContentLength)
+	copy(body, b)
+
+	return body, len(body), nil
+}
+
+// readChunked decodes a chunked body and skips its trailer.
+func readChunked(b []byte, limit int64) ([]byte, int, error) {
+	var (
+		body []byte
+		pos  int
+	)
+
+	for {
+		i := bytes.Index(b[pos:], crlf)
+		if i < 0 {
+			if len(b)-pos > maxChunkLine {
+				return nil, 0, errBadRequest
+			}
+
+			return nil, 0, errIncomplete
+		}
+
+		line := b[pos : pos+i]
+		if j := bytes.IndexByte(line, ';'); j >= 0 {
+			line = line[:j]
+		}
+
+		size, err := strconv.ParseInt(string(bytes.TrimSpace(line)), 16, 64)
+		if err != nil || size < 0 {
+			return nil, 0, errBadRequest
+		}
+		pos += i + 2
+
+		if size == 0 {
+			break
+		}
+
+		if int64(len(body))+size > limit {
+			return nil, 0, errBodyTooLarge
+		}
+
+		if int64(len(b)-pos) < size+2 {
+			return nil, 0, errIncomplete
+		}
+
+		end := pos + int(size)
+		if !bytes.Equal(b[end:end+2], crlf) {
+			return nil, 0, errBadRequest
+		}
+
+		body = append(body, b[pos:end]...)
+		pos = end + 2
+	}
+
+	// Trailer fields are dropped, up to the empty line.
+	for {
+		i := bytes.Index(b[pos:], crlf)
+		if i < 0 {
+			return nil, 0, errIncomplete
+		}
+
+		pos += i + 2
+		if i == 0 {
+			return body, pos, nil
+		}
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package httpd
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// response buffers what the handler writes, it is sent once the handler
+// returned.
+type response struct {
+	req    *http.Request
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponse(r *http.Request) *response {
+	return &response{
+		req:    r,
+		header: make(http.Header),
+	}
+}
+
+func (w *response) Header() http.Header {
+	return w.header
+}
+
+func (w *response) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *response) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+
+	return w.body.Write(b)
+}
+
+// writeTo appends the response to out.
+func (w *response) writeTo(out *bytes.Buffer, keepAlive bool) {
+	w.WriteHeader(http.StatusOK)
+
+	h := w.header
+	body := w.body.Bytes()
+
+	if bodyAllowed(w.status) {
+		if h.Get("Content-Length") == "" {
+			h.Set("Content-Length", strconv.Itoa(len(body)))
+		}
+
+		if h.Get("Content-Type") == "" && len(body) > 0 {
+			h.Set("Content-Type", http.DetectContentType(body))
+		}
+	} else {
+		h.Del("Content-Length")
+		body = nil
+	}
+
+	if h.Get("Date") == "" {
+		h.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+
+	switch {
+	case !keepAlive:
+		h.Set("Connection", "close")
+	case !w.req.ProtoAtLeast(1, 1):
+		h.Set("Connection", "keep-alive")
+	}
+
+	fmt.Fprintf(out, "HTTP/1.1 %d %s\r\n", w.status, http.StatusText(w.status))
+	h.Write(out)
+	out.WriteString("\r\n")
+
+	if w.req.Method != http.MethodHead {
+		out.Write(body)
+	}
+}
+
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// errorResponse is sent before closing a connection the request of which
+// couldn't be read.
+func errorResponse(status int) []byte {
+	return []byte(fmt.Sprintf("HTTP/1.1 %d %s\r\nConnection: close\r\nContent-Length: 0\r\n\r\n", status, http.StatusText(status)))
+}
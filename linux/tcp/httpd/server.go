@@ -0,0 +1,259 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package httpd
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+)
+
+const (
+	defaultMaxHeaderSize = 16 << 10
+	defaultMaxBodySize   = 1 << 20
+	minReadSpace         = 4096
+)
+
+var errClose = errors.New("connection closed by response")
+
+// Config is configuration for an HTTP server.
+type Config struct {
+	// MaxHeaderSize bounds the request line and header, 16 KiB by default.
+	MaxHeaderSize int
+
+	// MaxBodySize bounds a request body, 1 MiB by default.
+	MaxBodySize int64
+}
+
+// Server serves HTTP/1.1 with an http.Handler. It is a tcp.Handler, start
+// it with tcp.StartServer; a ReadIdleTimeout closes idle keep-alive
+// connections.
+//
+// Requests of a connection are handled one after the other on its read
+// task, pipelined ones included, and responses are buffered until the
+// handler returns. It suits small endpoints, not streaming.
+type Server struct {
+	conf     *Config
+	handler  http.Handler
+	sessions sync.Map
+}
+
+// NewServer creates a server calling h.
+func NewServer(c *Config, h http.Handler) *Server {
+	return &Server{
+		conf:    c,
+		handler: h,
+	}
+}
+
+// session is the read state of a connection.
+type session struct {
+	buf []byte
+
+	// Request the header of which was read, waiting for its body.
+	req       *http.Request
+	size      int
+	continued bool
+}
+
+// OnAccept is the tcp.Handler implementation.
+func (s *Server) OnAccept() error {
+	return nil
+}
+
+// OnClose is the tcp.Handler implementation.
+func (s *Server) OnClose(conn net.Conn) {
+	s.sessions.Delete(conn)
+}
+
+// OnError is the tcp.Handler implementation, it closes the connection.
+func (s *Server) OnError(conn net.Conn) {
+	conn.Close()
+}
+
+// OnReadMessage is the tcp.Handler implementation.
+func (s *Server) OnReadMessage(conn net.Conn) error {
+	v, ok := s.sessions.Load(conn)
+	if !ok {
+		v, _ = s.sessions.LoadOrStore(conn, &session{})
+	}
+	sess := v.(*session)
+
+	if cap(sess.buf)-len(sess.buf) < minReadSpace {
+		buf := make([]byte, len(sess.buf), 2*cap(sess.buf)+minReadSpace)
+		copy(buf, sess.buf)
+		sess.buf = buf
+	}
+
+	n, err := conn.Read(sess.buf[len(sess.buf):cap(sess.buf)])
+	sess.buf = sess.buf[:len(sess.buf)+n]
+
+	switch perr := s.process(conn.(*tcp.Conn), sess); perr {
+	case nil:
+	case errClose:
+		// Asked for, it's no read error.
+		conn.Close()
+		return nil
+	default:
+		return perr
+	}
+
+	return err
+}
+
+// process serves every complete request in the buffer, the responses go
+// out with a single write.
+func (s *Server) process(conn *tcp.Conn, sess *session) error {
+	var (
+		out  bytes.Buffer
+		off  int
+		perr error
+	)
+
+	for perr == nil {
+		if sess.req == nil {
+			r, n, err := readHeader(sess.buf[off:], s.maxHeaderSize())
+			if err == errIncomplete {
+				break
+			}
+
+			if err != nil {
+				perr = s.refuse(&out, err)
+				break
+			}
+
+			sess.req, sess.size = r, n
+		}
+
+		body, n, err := readBody(sess.req, sess.buf[off+sess.size:], s.maxBodySize())
+		if err == errIncomplete {
+			if !sess.continued && sess.req.Header.Get("Expect") == "100-continue" {
+				out.WriteString("HTTP/1.1 100 Continue\r\n\r\n")
+				sess.continued = true
+			}
+			break
+		}
+
+		if err != nil {
+			perr = s.refuse(&out, err)
+			break
+		}
+
+		r := sess.req
+		off += sess.size + n
+		sess.req, sess.size, sess.continued = nil, 0, false
+
+		if !s.serve(&out, conn, r, body) {
+			perr = errClose
+		}
+	}
+
+	sess.buf = sess.buf[:copy(sess.buf, sess.buf[off:])]
+
+	if out.Len() > 0 {
+		if _, err := conn.Write(out.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return perr
+}
+
+// serve runs the handler, it tells whether the connection is kept open.
+func (s *Server) serve(out *bytes.Buffer, conn *tcp.Conn, r *http.Request, body []byte) (keepAlive bool) {
+	r.Body = http.NoBody
+	if len(body) > 0 {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	r.RemoteAddr = conn.RemoteAddr().String()
+	r.TLS = conn.TLS()
+
+	w := newResponse(r)
+	keepAlive = !r.Close
+
+	func() {
+		defer func() {
+			if recover() != nil {
+				w = newResponse(r)
+				w.WriteHeader(http.StatusInternalServerError)
+				keepAlive = false
+			}
+		}()
+
+		s.handler.ServeHTTP(w, r)
+	}()
+
+	if w.header.Get("Connection") == "close" {
+		keepAlive = false
+	}
+
+	w.writeTo(out, keepAlive)
+
+	return keepAlive
+}
+
+// refuse answers a request that couldn't be read, then the connection is
+// closed.
+func (s *Server) refuse(out *bytes.Buffer, err error) error {
+	status := http.StatusBadRequest
+
+	switch err {
+	case errHeaderTooLarge:
+		status = http.StatusRequestHeaderFieldsTooLarge
+	case errBodyTooLarge:
+		status = http.StatusRequestEntityTooLarge
+	}
+
+	out.Write(errorResponse(status))
+
+	return err
+}
+
+func (s *Server) maxHeaderSize() int {
+	if s.conf.MaxHeaderSize > 0 {
+		return s.conf.MaxHeaderSize
+	}
+
+	return defaultMaxHeaderSize
+}
+
+func (s *Server) maxBodySize() int64 {
+	if s.conf.MaxBodySize > 0 {
+		return s.conf.MaxBodySize
+	}
+
+	return defaultMaxBodySize
+}
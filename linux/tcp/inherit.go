@@ -0,0 +1,180 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package tcp
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ListenersEnv lists the descriptors of the listeners a process inherited
+// from its parent, as comma separated numbers.
+const ListenersEnv = "NUTS_TCP_LISTENERS"
+
+// drainInterval is how often Shutdown checks for open connections.
+const drainInterval = 50 * time.Millisecond
+
+var inherit struct {
+	once      sync.Once
+	mu        sync.Mutex
+	listeners []net.Listener
+}
+
+// PassListeners arranges for cmd to inherit the listeners of the server,
+// a StartServer on the same address in the child adopts them instead of
+// binding. Both processes accept until the parent calls Shutdown, so no
+// connection is refused during a restart. cmd is started by the caller.
+func (s *Server) PassListeners(cmd *exec.Cmd) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var fds []string
+
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+
+	// Several servers may hand over their listeners to the same child.
+	kept := env[:0:0]
+	for _, kv := range env {
+		if v := strings.TrimPrefix(kv, ListenersEnv+"="); v != kv {
+			if v != "" {
+				fds = strings.Split(v, ",")
+			}
+			continue
+		}
+		kept = append(kept, kv)
+	}
+
+	for _, l := range s.listeners {
+		f, err := listenerFile(l.ln)
+		if err != nil {
+			return err
+		}
+
+		s.exported = append(s.exported, f)
+		cmd.ExtraFiles = append(cmd.ExtraFiles, f)
+
+		// ExtraFiles start after stdin, stdout and stderr.
+		fds = append(fds, strconv.Itoa(len(cmd.ExtraFiles)+2))
+	}
+
+	cmd.Env = append(kept, ListenersEnv+"="+strings.Join(fds, ","))
+
+	return nil
+}
+
+func listenerFile(ln net.Listener) (*os.File, error) {
+	switch l := ln.(type) {
+	case *net.TCPListener:
+		return l.File()
+	case *net.UnixListener:
+		// The socket file now belongs to the child as well.
+		l.SetUnlinkOnClose(false)
+		return l.File()
+	}
+
+	return nil, ErrNotSyscallConn
+}
+
+// inheritedListeners takes the inherited listeners bound to address, each
+// of them is only returned once.
+func inheritedListeners(network, address string) []net.Listener {
+	inherit.once.Do(func() {
+		v := os.Getenv(ListenersEnv)
+		if v == "" {
+			return
+		}
+		os.Unsetenv(ListenersEnv)
+
+		for _, s := range strings.Split(v, ",") {
+			fd, err := strconv.Atoi(s)
+			if err != nil {
+				continue
+			}
+
+			f := os.NewFile(uintptr(fd), "listener")
+			ln, err := net.FileListener(f)
+			f.Close()
+			if err != nil {
+				continue
+			}
+
+			inherit.listeners = append(inherit.listeners, ln)
+		}
+	})
+
+	inherit.mu.Lock()
+	defer inherit.mu.Unlock()
+
+	var (
+		taken []net.Listener
+		kept  = inherit.listeners[:0]
+	)
+
+	for _, ln := range inherit.listeners {
+		if sameAddress(network, address, ln.Addr()) {
+			taken = append(taken, ln)
+		} else {
+			kept = append(kept, ln)
+		}
+	}
+	inherit.listeners = kept
+
+	return taken
+}
+
+func sameAddress(network, address string, addr net.Addr) bool {
+	if network == "unix" {
+		return addr.Network() == "unix" && addr.String() == address
+	}
+
+	got, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+
+	want, err := net.ResolveTCPAddr(network, address)
+	if err != nil || want.Port != got.Port {
+		return false
+	}
+
+	if want.IP == nil || want.IP.IsUnspecified() {
+		return got.IP.IsUnspecified()
+	}
+
+	return want.IP.Equal(got.IP)
+}
@@ -0,0 +1,277 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package tcp
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// LimitAction is what happens to a connection over its rate limit.
+type LimitAction int
+
+const (
+	// Throttle stops reading the connection until its budget allows, the
+	// peer is slowed down by TCP flow control.
+	Throttle LimitAction = iota
+
+	// Drop reads and discards what arrives while over budget.
+	Drop
+
+	// Disconnect closes the connection.
+	Disconnect
+)
+
+var (
+	// ErrRateLimit means Config.RateLimit is invalid.
+	ErrRateLimit = errors.New("invalid rate limit")
+
+	// errLimited means a connection is over its budget.
+	errLimited = errors.New("rate limited")
+)
+
+// RateLimit caps the bytes and messages read per second, a message being a
+// call of OnReadMessage or OnReadBuffer. Budgets refill continuously and
+// hold at most one second worth. Zero means unlimited.
+type RateLimit struct {
+	// Per connection.
+	BytesPerSecond    int
+	MessagesPerSecond int
+
+	// Shared by all connections of the server.
+	GlobalBytesPerSecond    int
+	GlobalMessagesPerSecond int
+
+	// Action is taken on connections over budget, Throttle by default.
+	Action LimitAction
+}
+
+func (l *RateLimit) validate() error {
+	switch {
+	case l.BytesPerSecond < 0, l.MessagesPerSecond < 0:
+		return ErrRateLimit
+	case l.GlobalBytesPerSecond < 0, l.GlobalMessagesPerSecond < 0:
+		return ErrRateLimit
+	case l.Action < Throttle || l.Action > Disconnect:
+		return ErrRateLimit
+	}
+
+	return nil
+}
+
+// limiter holds the budgets of a connection or a server, nil when
+// unlimited.
+type limiter struct {
+	// Makes checking the budgets and taking a message one step, for the
+	// server's limiter shared by all connections.
+	mu       sync.Mutex
+	bytes    *bucket
+	messages *bucket
+}
+
+func newLimiter(bytes, messages int) *limiter {
+	if bytes <= 0 && messages <= 0 {
+		return nil
+	}
+
+	return &limiter{
+		bytes:    newBucket(bytes),
+		messages: newBucket(messages),
+	}
+}
+
+// admit takes a message when both budgets allow, otherwise it returns how
+// long to wait.
+func (l *limiter) admit(now time.Time) time.Duration {
+	if l == nil {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	d := l.wait(now)
+	if d == 0 {
+		l.messages.take(1)
+	}
+
+	return d
+}
+
+// refund gives back a message taken by admit.
+func (l *limiter) refund() {
+	if l != nil {
+		l.messages.take(-1)
+	}
+}
+
+func (l *limiter) wait(now time.Time) time.Duration {
+	if l == nil {
+		return 0
+	}
+
+	d := l.bytes.wait(now)
+	if m := l.messages.wait(now); m > d {
+		d = m
+	}
+
+	return d
+}
+
+// bucket is a token bucket. Bytes are taken once read, so it may go in
+// debt, which delays the next message until paid back.
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate int) *bucket {
+	if rate <= 0 {
+		return nil
+	}
+
+	return &bucket{
+		rate:   float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+// wait returns how long until the bucket holds a token.
+func (b *bucket) wait(now time.Time) time.Duration {
+	if b == nil {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(now)
+	if b.tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+func (b *bucket) take(n int) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	b.tokens -= float64(n)
+	b.mu.Unlock()
+}
+
+func (b *bucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		b.last = now
+	}
+
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+}
+
+// limitDelay returns how long c has to wait for its next message.
+func (c *Conn) limitDelay() time.Duration {
+	if c.limits == nil && c.server.limits == nil {
+		return 0
+	}
+
+	now := time.Now()
+
+	d := c.limits.wait(now)
+	if g := c.server.limits.wait(now); g > d {
+		d = g
+	}
+
+	return d
+}
+
+// admit takes a message from the budgets, errLimited means there is none
+// left.
+func (c *Conn) admit() error {
+	now := time.Now()
+
+	if c.limits.admit(now) > 0 {
+		return errLimited
+	}
+
+	if c.server.limits.admit(now) > 0 {
+		c.limits.refund()
+		return errLimited
+	}
+
+	return nil
+}
+
+// received records that n bytes were read.
+func (c *Conn) received(n int) {
+	atomic.StoreInt64(&c.lastRead, time.Now().UnixNano())
+
+	if c.limits != nil {
+		c.limits.bytes.take(n)
+	}
+
+	if c.server.limits != nil {
+		c.server.limits.bytes.take(n)
+	}
+}
+
+// discard reads and drops what's in the socket, without charging it.
+func (c *Conn) discard() error {
+	b := getBuffer(c.readClass)
+	defer b.Release()
+
+	for {
+		n, nonblock, err := c.readNonblock(b.B)
+
+		switch {
+		case err == unix.EAGAIN:
+			return nil
+		case err != nil:
+			return err
+		case n == 0:
+			return io.EOF
+		case !nonblock || n < len(b.B):
+			return nil
+		}
+	}
+}
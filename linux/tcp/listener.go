@@ -0,0 +1,308 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package tcp
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/mailru/easygo/netpoll"
+	"golang.org/x/sys/unix"
+)
+
+var (
+	// ErrListenerExists means a listener of the same name is already open.
+	ErrListenerExists = errors.New("listener already exists")
+
+	// ErrNoListener means no open listener has the given name.
+	ErrNoListener = errors.New("no such listener")
+
+	// ErrServerClosed means the server was closed.
+	ErrServerClosed = errors.New("server closed")
+)
+
+// Listener is an address a Server accepts on. Connections tell handlers
+// which one accepted them, see Conn.Listener.
+type Listener struct {
+	// Name identifies the listener, it must be unique within a Server.
+	Name string
+
+	// Network and Address are as in Config, Network defaults to "tcp".
+	Network string
+	Address string
+
+	// Socket replaces Config.Socket for this listener when not nil.
+	Socket *SocketOptions
+}
+
+func (l *Listener) network() string {
+	if l.Network == "" {
+		return "tcp"
+	}
+
+	return l.Network
+}
+
+// validate checks l for a server with the given number of reactors and
+// default socket options.
+func (l *Listener) validate(reactors int, socket *SocketOptions) error {
+	switch l.network() {
+	case "tcp", "tcp4", "tcp6":
+	case "unix":
+		if reactors > 1 {
+			return ErrReusePortNetwork
+		}
+	default:
+		return ErrNetwork
+	}
+
+	if l.Socket != nil {
+		socket = l.Socket
+	}
+
+	return socket.validate(l.network())
+}
+
+// listener is a socket of a Listener, watched by the poller of a reactor.
+// A Listener has one per reactor when bound with SO_REUSEPORT.
+type listener struct {
+	conf       *Listener
+	ln         net.Listener
+	rawLn      syscall.RawConn
+	acceptDesc *netpoll.Desc
+	reactor    *reactor
+	socket     *SocketOptions
+	closed     int32
+
+	// Accept backoff, only used on the poller's goroutine.
+	backoff time.Duration
+
+	// Descriptor reserved for shedding connections, see shed.
+	spareMu sync.Mutex
+	spare   int
+}
+
+// newListener takes ownership of ln.
+func (s *Server) newListener(conf *Listener, ln net.Listener, r *reactor) *listener {
+	return &listener{
+		conf:    conf,
+		ln:      ln,
+		reactor: r,
+		socket:  s.socket(conf),
+		spare:   -1,
+	}
+}
+
+func (s *Server) socket(conf *Listener) *SocketOptions {
+	if conf.Socket != nil {
+		return conf.Socket
+	}
+
+	return &s.conf.Socket
+}
+
+func (l *listener) close() error {
+	if !atomic.CompareAndSwapInt32(&l.closed, 0, 1) {
+		return nil
+	}
+
+	if l.acceptDesc != nil {
+		l.reactor.poller.Stop(l.acceptDesc)
+		l.acceptDesc.Close()
+	}
+
+	l.spareMu.Lock()
+	if l.spare >= 0 {
+		unix.Close(l.spare)
+		l.spare = -1
+	}
+	l.spareMu.Unlock()
+
+	return l.ln.Close()
+}
+
+func (l *listener) isClosed() bool {
+	return atomic.LoadInt32(&l.closed) != 0
+}
+
+// Listen starts accepting on another address, on every reactor when bound
+// with SO_REUSEPORT. Inherited listeners bound to the address are adopted,
+// see PassListeners.
+func (s *Server) Listen(conf Listener) error {
+	if err := conf.validate(len(s.reactors), &s.conf.Socket); err != nil {
+		return err
+	}
+
+	return s.listen(&conf, inheritedListeners(conf.network(), conf.Address))
+}
+
+// listen binds conf on the reactors, or adopts the inherited listeners,
+// which it takes ownership of.
+func (s *Server) listen(conf *Listener, inherited []net.Listener) error {
+	started, err := s.bind(conf, inherited)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range started {
+		s.listening(l)
+	}
+
+	return nil
+}
+
+func (s *Server) bind(conf *Listener, inherited []net.Listener) ([]*listener, error) {
+	var started []*listener
+
+	fail := func(err error) ([]*listener, error) {
+		for _, l := range started {
+			l.close()
+		}
+
+		for i := len(started); i < len(inherited); i++ {
+			inherited[i].Close()
+		}
+
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if atomic.LoadInt32(&s.closed) != 0 {
+		return fail(ErrServerClosed)
+	}
+
+	for _, l := range s.listeners {
+		if l.conf.Name == conf.Name {
+			return fail(ErrListenerExists)
+		}
+	}
+
+	n := len(s.reactors)
+	if len(inherited) > 0 {
+		n = len(inherited)
+	}
+
+	address := conf.Address
+	for i := 0; i < n; i++ {
+		var (
+			ln  net.Listener
+			err error
+		)
+
+		if i < len(inherited) {
+			ln = inherited[i]
+		} else if ln, err = listen(conf.network(), address, n > 1, s.socket(conf)); err != nil {
+			return fail(err)
+		}
+
+		// Handlers see the address actually bound, the others must bind
+		// the very port the first one got.
+		if i == 0 {
+			conf.Network = conf.network()
+			conf.Address = ln.Addr().String()
+			address = conf.Address
+		}
+
+		l := s.newListener(conf, ln, s.reactors[i%len(s.reactors)])
+		started = append(started, l)
+
+		if err = s.accept(l); err != nil {
+			return fail(err)
+		}
+	}
+
+	s.listeners = append(s.listeners, started...)
+
+	return started, nil
+}
+
+// Unlisten stops accepting on the named listener. The connections it
+// accepted stay open.
+func (s *Server) Unlisten(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		err   error
+		found bool
+		kept  = s.listeners[:0]
+	)
+
+	for _, l := range s.listeners {
+		if l.conf.Name != name {
+			kept = append(kept, l)
+			continue
+		}
+
+		found = true
+		if e := l.close(); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	// Drop the references left past the end.
+	for i := len(kept); i < len(s.listeners); i++ {
+		s.listeners[i] = nil
+	}
+	s.listeners = kept
+
+	if !found {
+		return ErrNoListener
+	}
+
+	return err
+}
+
+// Listeners returns the listeners the server accepts on, with the
+// networks and addresses actually bound.
+func (s *Server) Listeners() []Listener {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		confs []Listener
+		seen  = make(map[*Listener]bool)
+	)
+
+	for _, l := range s.listeners {
+		if !seen[l.conf] {
+			seen[l.conf] = true
+			confs = append(confs, *l.conf)
+		}
+	}
+
+	return confs
+}
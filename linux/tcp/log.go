@@ -0,0 +1,118 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package tcp
+
+import (
+	"net"
+)
+
+// Logger receives a message per event of the server's life, followed by
+// alternating keys and values, the form structured loggers take.
+type Logger interface {
+	Log(msg string, keyvals ...interface{})
+}
+
+// LoggerFunc adapts a function to Logger.
+type LoggerFunc func(msg string, keyvals ...interface{})
+
+// Log calls f.
+func (f LoggerFunc) Log(msg string, keyvals ...interface{}) {
+	f(msg, keyvals...)
+}
+
+func (s *Server) log(msg string, keyvals ...interface{}) {
+	if s.conf.Logger != nil {
+		s.conf.Logger.Log(msg, keyvals...)
+	}
+}
+
+func (s *Server) listening(l *listener) {
+	s.log("listening", "reactor", l.reactor.id, "listener", l.conf.Name, "addr", l.ln.Addr())
+
+	if s.conf.OnListen != nil {
+		s.conf.OnListen(l.ln.Addr())
+	}
+}
+
+func (s *Server) accepted(c *Conn) {
+	s.log("accepted", "reactor", c.reactor.id, "remote", c.RemoteAddr())
+
+	if s.conf.OnConnAccept != nil {
+		s.conf.OnConnAccept(c)
+	}
+}
+
+// reject closes a connection the server gave up on before serving it.
+func (s *Server) reject(l *listener, conn net.Conn, err error) {
+	conn.Close()
+
+	s.log("rejected", "reactor", l.reactor.id, "listener", l.conf.Name, "remote", conn.RemoteAddr(), "err", err)
+
+	if s.conf.OnConnReject != nil {
+		s.conf.OnConnReject(conn.RemoteAddr(), err)
+	}
+}
+
+func (s *Server) acceptError(l *listener, err error) {
+	s.log("accept error", "reactor", l.reactor.id, "listener", l.conf.Name, "addr", l.ln.Addr(), "err", err)
+
+	if s.conf.OnServerError != nil {
+		s.conf.OnServerError(&net.OpError{
+			Op:   "accept",
+			Net:  l.ln.Addr().Network(),
+			Addr: l.ln.Addr(),
+			Err:  err,
+		})
+	}
+}
+
+func (s *Server) readError(c *Conn, err error) {
+	s.log("read error", "reactor", c.reactor.id, "remote", c.RemoteAddr(), "err", err)
+
+	if s.conf.OnReadError != nil {
+		s.conf.OnReadError(c, err)
+	}
+}
+
+func (s *Server) pollerError(id int, err error) {
+	s.log("poller error", "reactor", id, "err", err)
+
+	if s.conf.OnPollerError != nil {
+		s.conf.OnPollerError(err)
+	}
+}
+
+func (s *Server) shutdown() {
+	s.log("shutdown", "addr", s.Addr())
+
+	if s.conf.OnShutdown != nil {
+		s.conf.OnShutdown()
+	}
+}
@@ -0,0 +1,401 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package tcp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultProxyTimeout = 5 * time.Second
+
+	// A v1 header is at most 107 bytes, CRLF included.
+	maxProxyV1Size = 107
+)
+
+// Types of the TLVs of a v2 header.
+const (
+	PP2TypeALPN      = 0x01
+	PP2TypeAuthority = 0x02
+	PP2TypeCRC32C    = 0x03
+	PP2TypeNoop      = 0x04
+	PP2TypeUniqueID  = 0x05
+	PP2TypeSSL       = 0x20
+	PP2TypeNetNS     = 0x30
+)
+
+var (
+	// ErrProxyHeader means a trusted peer sent an invalid PROXY header.
+	ErrProxyHeader = errors.New("invalid PROXY header")
+
+	// ErrProxyChecksum means the CRC32C of a v2 header doesn't match.
+	ErrProxyChecksum = errors.New("PROXY header checksum mismatch")
+
+	// ErrNoTrustedProxy means the PROXY protocol was enabled without
+	// trusted networks.
+	ErrNoTrustedProxy = errors.New("PROXY protocol needs trusted networks")
+
+	proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+	castagnoli       = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// ProxyConfig enables the PROXY protocol, as sent by HAProxy and most load
+// balancers.
+type ProxyConfig struct {
+	// Trusted lists the addresses, as IPs or CIDRs, of the load balancers.
+	// Connections from them must start with a v1 or v2 header, any other is
+	// served as is, so clients can't spoof their address. All peers of a
+	// unix socket are trusted.
+	Trusted []string
+
+	// Timeout bounds reading the header, 5 seconds by default.
+	Timeout time.Duration
+}
+
+// ProxyHeader is the PROXY header a connection started with.
+type ProxyHeader struct {
+	Version int
+
+	// Local is set for connections the proxy made on its own, such as
+	// health checks. They carry no addresses.
+	Local bool
+
+	// Source is the address of the client, Destination the one it
+	// connected to. Both are nil when the proxy didn't know them.
+	Source      net.Addr
+	Destination net.Addr
+
+	// TLVs are the additional fields of a v2 header.
+	TLVs []TLV
+}
+
+// TLV is a type-length-value field of a v2 header.
+type TLV struct {
+	Type  byte
+	Value []byte
+}
+
+// TLV returns the value of the first field of type t.
+func (h *ProxyHeader) TLV(t byte) ([]byte, bool) {
+	for _, tlv := range h.TLVs {
+		if tlv.Type == t {
+			return tlv.Value, true
+		}
+	}
+
+	return nil, false
+}
+
+func (p *ProxyConfig) networks() ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(p.Trusted))
+
+	for _, s := range p.Trusted {
+		if ip := net.ParseIP(s); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+
+		nets = append(nets, n)
+	}
+
+	return nets, nil
+}
+
+func (p *ProxyConfig) timeout() time.Duration {
+	if p.Timeout > 0 {
+		return p.Timeout
+	}
+
+	return defaultProxyTimeout
+}
+
+// proxied tells whether conn comes from a trusted proxy.
+func (s *Server) proxied(conn net.Conn) bool {
+	if s.conf.Proxy == nil {
+		return false
+	}
+
+	// Only local processes reach a Unix socket.
+	addr, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return true
+	}
+
+	for _, n := range s.proxies {
+		if n.Contains(addr.IP) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// proxy reads the PROXY header on its own goroutine, like the TLS
+// handshake that may follow it.
+func (s *Server) proxy(l *listener, raw net.Conn) {
+	raw.SetDeadline(time.Now().Add(s.conf.Proxy.timeout()))
+	hdr, err := readProxyHeader(raw)
+	if err != nil {
+		s.reject(l, raw, err)
+		return
+	}
+	raw.SetDeadline(time.Time{})
+
+	if s.conf.TLSConfig != nil {
+		s.handshake(l, raw, hdr)
+		return
+	}
+
+	s.start(l, raw, raw, hdr)
+}
+
+// readProxyHeader reads a v1 or v2 header, without consuming anything
+// past it.
+func readProxyHeader(r io.Reader) (*ProxyHeader, error) {
+	var b [16]byte
+
+	if _, err := io.ReadFull(r, b[:6]); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case string(b[:6]) == "PROXY ":
+		return readProxyV1(r, b[:6])
+	case bytes.Equal(b[:6], proxyV2Signature[:6]):
+		if _, err := io.ReadFull(r, b[6:]); err != nil {
+			return nil, err
+		}
+
+		if !bytes.Equal(b[:12], proxyV2Signature) {
+			return nil, ErrProxyHeader
+		}
+
+		body := make([]byte, binary.BigEndian.Uint16(b[14:]))
+		if _, err := io.ReadFull(r, body); err != nil {
+			return nil, err
+		}
+
+		return parseProxyV2(b[:], body)
+	}
+
+	return nil, ErrProxyHeader
+}
+
+// readProxyV1 reads the rest of a v1 header byte by byte, it ends with the
+// first CRLF.
+func readProxyV1(r io.Reader, prefix []byte) (*ProxyHeader, error) {
+	line := append(make([]byte, 0, maxProxyV1Size), prefix...)
+
+	var b [1]byte
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) == maxProxyV1Size {
+			return nil, ErrProxyHeader
+		}
+
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, err
+		}
+		line = append(line, b[0])
+	}
+
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	hdr := &ProxyHeader{Version: 1}
+
+	switch {
+	case len(fields) >= 2 && fields[1] == "UNKNOWN":
+		return hdr, nil
+	case len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6"):
+		return nil, ErrProxyHeader
+	}
+
+	src, err := proxyV1Addr(fields[1], fields[2], fields[4])
+	if err != nil {
+		return nil, err
+	}
+
+	dst, err := proxyV1Addr(fields[1], fields[3], fields[5])
+	if err != nil {
+		return nil, err
+	}
+
+	hdr.Source, hdr.Destination = src, dst
+
+	return hdr, nil
+}
+
+func proxyV1Addr(family, host, port string) (*net.TCPAddr, error) {
+	ip := net.ParseIP(host)
+	if ip == nil || (ip.To4() != nil) != (family == "TCP4") {
+		return nil, ErrProxyHeader
+	}
+
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, ErrProxyHeader
+	}
+
+	return &net.TCPAddr{IP: ip, Port: int(p)}, nil
+}
+
+// parseProxyV2 parses the 16 bytes fixed part of a v2 header and the
+// addresses and TLVs following it.
+func parseProxyV2(fixed, body []byte) (*ProxyHeader, error) {
+	if fixed[12]>>4 != 2 {
+		return nil, ErrProxyHeader
+	}
+
+	hdr := &ProxyHeader{Version: 2}
+
+	switch fixed[12] & 0x0f {
+	case 0:
+		// Addresses and TLVs of LOCAL connections are to be ignored.
+		hdr.Local = true
+		return hdr, nil
+	case 1:
+	default:
+		return nil, ErrProxyHeader
+	}
+
+	var (
+		size       int
+		family     = fixed[13] >> 4
+		stream     = fixed[13]&0x0f == 1
+		datagram   = fixed[13]&0x0f == 2
+		knownProto = stream || datagram
+	)
+
+	// An unspecified family has no address block, the TLVs follow.
+	switch family {
+	case 0:
+	case 1:
+		size = 2*net.IPv4len + 4
+	case 2:
+		size = 2*net.IPv6len + 4
+	case 3:
+		size = 2 * 108
+	default:
+		return nil, ErrProxyHeader
+	}
+
+	if len(body) < size {
+		return nil, ErrProxyHeader
+	}
+
+	if knownProto {
+		switch family {
+		case 1, 2:
+			n := (size - 4) / 2
+			src, dst := net.IP(body[:n]), net.IP(body[n:2*n])
+			sport := int(binary.BigEndian.Uint16(body[2*n:]))
+			dport := int(binary.BigEndian.Uint16(body[2*n+2:]))
+
+			if stream {
+				hdr.Source = &net.TCPAddr{IP: dupIP(src), Port: sport}
+				hdr.Destination = &net.TCPAddr{IP: dupIP(dst), Port: dport}
+			} else {
+				hdr.Source = &net.UDPAddr{IP: dupIP(src), Port: sport}
+				hdr.Destination = &net.UDPAddr{IP: dupIP(dst), Port: dport}
+			}
+		case 3:
+			network := "unix"
+			if datagram {
+				network = "unixgram"
+			}
+
+			hdr.Source = &net.UnixAddr{Name: cString(body[:108]), Net: network}
+			hdr.Destination = &net.UnixAddr{Name: cString(body[108:size]), Net: network}
+		}
+	}
+
+	for rest := body[size:]; len(rest) > 0; {
+		if len(rest) < 3 {
+			return nil, ErrProxyHeader
+		}
+
+		n := int(binary.BigEndian.Uint16(rest[1:]))
+		if len(rest) < 3+n {
+			return nil, ErrProxyHeader
+		}
+
+		tlv := TLV{Type: rest[0], Value: rest[3 : 3+n]}
+		if tlv.Type == PP2TypeCRC32C {
+			if n != 4 || !proxyChecksum(fixed, body, tlv.Value) {
+				return nil, ErrProxyChecksum
+			}
+		}
+
+		hdr.TLVs = append(hdr.TLVs, tlv)
+		rest = rest[3+n:]
+	}
+
+	return hdr, nil
+}
+
+// proxyChecksum checks the CRC32C of the whole header, computed with the
+// value of its TLV set to zero. value is a slice of body.
+func proxyChecksum(fixed, body, value []byte) bool {
+	want := binary.BigEndian.Uint32(value)
+
+	copy(value, []byte{0, 0, 0, 0})
+	sum := crc32.Update(crc32.Checksum(fixed, castagnoli), castagnoli, body)
+	binary.BigEndian.PutUint32(value, want)
+
+	return sum == want
+}
+
+func dupIP(ip net.IP) net.IP {
+	return append(net.IP(nil), ip...)
+}
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+
+	return string(b)
+}
@@ -0,0 +1,320 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package tcp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// proxyV2 builds a v2 header, with a CRC32C TLV appended when sum is set.
+// A checksum other than zero replaces the right one.
+func proxyV2(command, family byte, addrs []byte, tlvs []TLV, sum bool, checksum uint32) []byte {
+	var body []byte
+	body = append(body, addrs...)
+
+	for _, tlv := range tlvs {
+		body = append(body, tlv.Type, 0, 0)
+		binary.BigEndian.PutUint16(body[len(body)-2:], uint16(len(tlv.Value)))
+		body = append(body, tlv.Value...)
+	}
+
+	if sum {
+		body = append(body, PP2TypeCRC32C, 0, 4, 0, 0, 0, 0)
+	}
+
+	b := append([]byte(nil), proxyV2Signature...)
+	b = append(b, command, family, 0, 0)
+	binary.BigEndian.PutUint16(b[14:], uint16(len(body)))
+	b = append(b, body...)
+
+	if sum {
+		if checksum == 0 {
+			checksum = crc32.Checksum(b, castagnoli)
+		}
+		binary.BigEndian.PutUint32(b[len(b)-4:], checksum)
+	}
+
+	return b
+}
+
+func tcpAddrs(src, dst string, sport, dport uint16) []byte {
+	b := append(net.ParseIP(src).To4(), net.ParseIP(dst).To4()...)
+	if b == nil || len(b) != 2*net.IPv4len {
+		b = append(net.ParseIP(src).To16(), net.ParseIP(dst).To16()...)
+	}
+
+	b = binary.BigEndian.AppendUint16(b, sport)
+	return binary.BigEndian.AppendUint16(b, dport)
+}
+
+func unixAddrs(src, dst string) []byte {
+	b := make([]byte, 2*108)
+	copy(b, src)
+	copy(b[108:], dst)
+
+	return b
+}
+
+func TestReadProxyHeader(t *testing.T) {
+	alpn := TLV{Type: PP2TypeALPN, Value: []byte("h2")}
+	authority := TLV{Type: PP2TypeAuthority, Value: []byte("example.com")}
+	crc := TLV{Type: PP2TypeCRC32C}
+
+	tcp4 := tcpAddrs("192.0.2.1", "198.51.100.1", 56324, 443)
+
+	for _, tc := range []struct {
+		name  string
+		input []byte
+		want  *ProxyHeader
+		err   error
+	}{
+		{
+			name:  "v1 tcp4",
+			input: []byte("PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n"),
+			want: &ProxyHeader{
+				Version:     1,
+				Source:      &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 56324},
+				Destination: &net.TCPAddr{IP: net.ParseIP("198.51.100.1"), Port: 443},
+			},
+		},
+		{
+			name:  "v1 tcp6",
+			input: []byte("PROXY TCP6 2001:db8::1 2001:db8::2 1234 80\r\n"),
+			want: &ProxyHeader{
+				Version:     1,
+				Source:      &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 1234},
+				Destination: &net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 80},
+			},
+		},
+		{
+			name:  "v1 unknown",
+			input: []byte("PROXY UNKNOWN\r\n"),
+			want:  &ProxyHeader{Version: 1},
+		},
+		{
+			name:  "v1 unknown with addresses",
+			input: []byte("PROXY UNKNOWN ffff::1 ffff::2 1 2\r\n"),
+			want:  &ProxyHeader{Version: 1},
+		},
+		{
+			name:  "v1 family mismatch",
+			input: []byte("PROXY TCP4 2001:db8::1 198.51.100.1 1234 80\r\n"),
+			err:   ErrProxyHeader,
+		},
+		{
+			name:  "v1 bad port",
+			input: []byte("PROXY TCP4 192.0.2.1 198.51.100.1 70000 80\r\n"),
+			err:   ErrProxyHeader,
+		},
+		{
+			name:  "v1 missing field",
+			input: []byte("PROXY TCP4 192.0.2.1 198.51.100.1 80\r\n"),
+			err:   ErrProxyHeader,
+		},
+		{
+			name:  "v1 bare newline",
+			input: []byte("PROXY TCP4 192.0.2.1 198.51.100.1 1 2\n"),
+			err:   io.EOF,
+		},
+		{
+			name:  "v1 truncated",
+			input: []byte("PROXY TCP4 192.0.2.1"),
+			err:   io.EOF,
+		},
+		{
+			name:  "v1 oversized",
+			input: []byte("PROXY UNKNOWN " + strings.Repeat("x", 200) + "\r\n"),
+			err:   ErrProxyHeader,
+		},
+		{
+			name:  "not a header",
+			input: []byte("GET / HTTP/1.1\r\n\r\n"),
+			err:   ErrProxyHeader,
+		},
+		{
+			name:  "v2 local",
+			input: proxyV2(0x20, 0x00, nil, nil, false, 0),
+			want:  &ProxyHeader{Version: 2, Local: true},
+		},
+		{
+			name:  "v2 local ignores addresses",
+			input: proxyV2(0x20, 0x11, tcp4, nil, false, 0),
+			want:  &ProxyHeader{Version: 2, Local: true},
+		},
+		{
+			name:  "v2 tcp4",
+			input: proxyV2(0x21, 0x11, tcp4, nil, false, 0),
+			want: &ProxyHeader{
+				Version:     2,
+				Source:      &net.TCPAddr{IP: net.ParseIP("192.0.2.1").To4(), Port: 56324},
+				Destination: &net.TCPAddr{IP: net.ParseIP("198.51.100.1").To4(), Port: 443},
+			},
+		},
+		{
+			name:  "v2 udp6",
+			input: proxyV2(0x21, 0x22, tcpAddrs("2001:db8::1", "2001:db8::2", 53, 5353), nil, false, 0),
+			want: &ProxyHeader{
+				Version:     2,
+				Source:      &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 53},
+				Destination: &net.UDPAddr{IP: net.ParseIP("2001:db8::2"), Port: 5353},
+			},
+		},
+		{
+			name:  "v2 unix",
+			input: proxyV2(0x21, 0x31, unixAddrs("/run/client", "/run/server"), nil, false, 0),
+			want: &ProxyHeader{
+				Version:     2,
+				Source:      &net.UnixAddr{Name: "/run/client", Net: "unix"},
+				Destination: &net.UnixAddr{Name: "/run/server", Net: "unix"},
+			},
+		},
+		{
+			name:  "v2 unknown protocol",
+			input: proxyV2(0x21, 0x10, tcp4, nil, false, 0),
+			want:  &ProxyHeader{Version: 2},
+		},
+		{
+			name:  "v2 tlvs",
+			input: proxyV2(0x21, 0x11, tcp4, []TLV{alpn, authority}, false, 0),
+			want: &ProxyHeader{
+				Version:     2,
+				Source:      &net.TCPAddr{IP: net.ParseIP("192.0.2.1").To4(), Port: 56324},
+				Destination: &net.TCPAddr{IP: net.ParseIP("198.51.100.1").To4(), Port: 443},
+				TLVs:        []TLV{alpn, authority},
+			},
+		},
+		{
+			name:  "v2 checksum",
+			input: proxyV2(0x21, 0x11, tcp4, []TLV{alpn}, true, 0),
+			want: &ProxyHeader{
+				Version:     2,
+				Source:      &net.TCPAddr{IP: net.ParseIP("192.0.2.1").To4(), Port: 56324},
+				Destination: &net.TCPAddr{IP: net.ParseIP("198.51.100.1").To4(), Port: 443},
+				TLVs:        []TLV{alpn, crc},
+			},
+		},
+		{
+			name:  "v2 bad checksum",
+			input: proxyV2(0x21, 0x11, tcp4, []TLV{alpn}, true, 0xdeadbeef),
+			err:   ErrProxyChecksum,
+		},
+		{
+			name:  "v2 short checksum",
+			input: proxyV2(0x21, 0x11, tcp4, []TLV{{Type: PP2TypeCRC32C, Value: []byte{1, 2}}}, false, 0),
+			err:   ErrProxyChecksum,
+		},
+		{
+			name:  "v2 unspecified family tlvs",
+			input: proxyV2(0x21, 0x00, nil, []TLV{authority}, true, 0),
+			want:  &ProxyHeader{Version: 2, TLVs: []TLV{authority, crc}},
+		},
+		{
+			name:  "v2 unspecified family bad checksum",
+			input: proxyV2(0x21, 0x00, nil, []TLV{authority}, true, 0xdeadbeef),
+			err:   ErrProxyChecksum,
+		},
+		{
+			name:  "v2 unknown family",
+			input: proxyV2(0x21, 0x41, tcp4, nil, false, 0),
+			err:   ErrProxyHeader,
+		},
+		{
+			name:  "v2 truncated tlv",
+			input: proxyV2(0x21, 0x11, append(tcp4, PP2TypeALPN, 0, 10, 'h', '2'), nil, false, 0),
+			err:   ErrProxyHeader,
+		},
+		{
+			name:  "v2 trailing byte",
+			input: proxyV2(0x21, 0x11, append(tcp4, 0), nil, false, 0),
+			err:   ErrProxyHeader,
+		},
+		{
+			name:  "v2 short address block",
+			input: proxyV2(0x21, 0x21, tcp4, nil, false, 0),
+			err:   ErrProxyHeader,
+		},
+		{
+			name:  "v2 bad version",
+			input: proxyV2(0x11, 0x11, tcp4, nil, false, 0),
+			err:   ErrProxyHeader,
+		},
+		{
+			name:  "v2 bad command",
+			input: proxyV2(0x22, 0x11, tcp4, nil, false, 0),
+			err:   ErrProxyHeader,
+		},
+		{
+			name:  "v2 bad signature",
+			input: append([]byte("\r\n\r\n\x00\rXQUIT\n"), 0x21, 0x11, 0, 0),
+			err:   ErrProxyHeader,
+		},
+		{
+			name:  "v2 truncated body",
+			input: proxyV2(0x21, 0x11, tcp4, nil, false, 0)[:20],
+			err:   io.ErrUnexpectedEOF,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// What follows the header is left to the connection.
+			r := bytes.NewReader(append(append([]byte(nil), tc.input...), "rest"...))
+
+			hdr, err := readProxyHeader(r)
+			if err != tc.err {
+				t.Fatalf("error %v, want %v", err, tc.err)
+			}
+
+			if tc.err != nil {
+				return
+			}
+
+			// Checksums were verified by the parser.
+			for i := range hdr.TLVs {
+				if hdr.TLVs[i].Type == PP2TypeCRC32C {
+					hdr.TLVs[i].Value = nil
+				}
+			}
+
+			if !reflect.DeepEqual(hdr, tc.want) {
+				t.Fatalf("header %+v, want %+v", hdr, tc.want)
+			}
+
+			if rest, _ := io.ReadAll(r); string(rest) != "rest" {
+				t.Fatalf("left %q", rest)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package tcp
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/fengyfei/nuts/scheduler"
+	"github.com/mailru/easygo/netpoll"
+	"golang.org/x/sys/unix"
+)
+
+// reactor is a poller, together with the listeners it watches and the
+// connections they accepted.
+type reactor struct {
+	// Counters, accessed atomically.
+	accepted uint64
+	reads    uint64
+	limited  uint64
+	active   int64
+
+	id        int
+	poller    netpoll.Poller
+	scheduler *scheduler.Pool
+}
+
+// ReactorStats is a snapshot of the counters of a reactor.
+type ReactorStats struct {
+	Reactor  int
+	Accepted uint64 // Connections accepted since start
+	Active   int64  // Connections currently open
+	Reads    uint64 // Read tasks scheduled on the pool
+	Limited  uint64 // Times a connection went over its rate limit
+}
+
+// newReactor creates a reactor, onWaitError is told about the errors of
+// its poller.
+func newReactor(id int, pool *scheduler.Pool, onWaitError func(error)) (*reactor, error) {
+	p, err := netpoll.New(&netpoll.Config{
+		OnWaitError: onWaitError,
+	})
+	if err != nil {
+		return nil, ErrEpollCreate
+	}
+
+	return &reactor{
+		id:        id,
+		poller:    p,
+		scheduler: pool,
+	}, nil
+}
+
+// listen binds with SO_REUSEPORT when asked, so that the kernel balances
+// incoming connections among several listeners on the same address.
+func listen(network, address string, reusePort bool, opts *SocketOptions) (net.Listener, error) {
+	lc := net.ListenConfig{
+		Control: func(_, _ string, rc syscall.RawConn) error {
+			var err error
+
+			if cerr := rc.Control(func(fd uintptr) {
+				if reusePort {
+					err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+				}
+
+				if err == nil {
+					err = opts.listener(int(fd))
+				}
+			}); cerr != nil {
+				return cerr
+			}
+
+			return err
+		},
+	}
+
+	ln, err := lc.Listen(context.Background(), network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = opts.backlog(ln); err != nil {
+		ln.Close()
+		return nil, err
+	}
+
+	return ln, nil
+}
+
+func (r *reactor) stats() ReactorStats {
+	return ReactorStats{
+		Reactor:  r.id,
+		Accepted: atomic.LoadUint64(&r.accepted),
+		Active:   atomic.LoadInt64(&r.active),
+		Reads:    atomic.LoadUint64(&r.reads),
+		Limited:  atomic.LoadUint64(&r.limited),
+	}
+}
+
+// close stops the poller and its goroutine. netpoll.Poller has no Close,
+// the epoll instance behind it does.
+func (r *reactor) close() error {
+	if p, ok := r.poller.(io.Closer); ok {
+		return p.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+	"github.com/fengyfei/nuts/scheduler"
+)
+
+var (
+	// ErrShutdown means the connection closed before the response arrived.
+	ErrShutdown = errors.New("connection is shut down")
+
+	// ErrServerBusy means the server had no room to run the call.
+	ErrServerBusy = errors.New("server busy")
+)
+
+// ServerError is an error returned by a remote method.
+type ServerError string
+
+func (e ServerError) Error() string {
+	return string(e)
+}
+
+// Client calls remote methods over pooled connections. Calls to the same
+// address share connections, each waits for the response carrying its id.
+type Client struct {
+	conns    *tcp.Client
+	nextID   uint64
+	sessions sync.Map
+}
+
+// NewClient creates a client, responses are read on pool.
+func NewClient(conf *tcp.ClientConfig, pool *scheduler.Pool) (*Client, error) {
+	c := &Client{}
+
+	conns, err := tcp.NewClient(conf, (*clientHandler)(c), pool)
+	if err != nil {
+		return nil, err
+	}
+	c.conns = conns
+
+	return c, nil
+}
+
+// Call invokes method at address and waits for the response, or until ctx
+// is done, dialing included.
+func (c *Client) Call(ctx context.Context, address, method string, payload []byte) ([]byte, error) {
+	conn, err := c.conns.GetContext(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &frame{
+		id:      atomic.AddUint64(&c.nextID, 1),
+		kind:    kindRequest,
+		method:  method,
+		payload: payload,
+	}
+
+	b, err := req.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := c.session(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	ch, ok := s.add(req.id)
+	if !ok {
+		return nil, ErrShutdown
+	}
+
+	if _, err = conn.Write(b); err != nil {
+		s.done(req.id)
+		return nil, err
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return nil, ErrShutdown
+		}
+
+		switch resp.kind {
+		case kindError:
+			return nil, ServerError(resp.payload)
+		case kindBusy:
+			return nil, ErrServerBusy
+		}
+
+		return resp.payload, nil
+
+	case <-ctx.Done():
+		s.done(req.id)
+		return nil, ctx.Err()
+	}
+}
+
+// Close closes the client's connections, pending calls fail with
+// ErrShutdown.
+func (c *Client) Close() error {
+	return c.conns.Close()
+}
+
+// session returns the session of conn, created by OnOpen and removed by
+// OnClose.
+func (c *Client) session(conn net.Conn) (*session, error) {
+	v, ok := c.sessions.Load(conn)
+	if !ok {
+		return nil, ErrShutdown
+	}
+
+	return v.(*session), nil
+}
+
+// clientHandler keeps the tcp.Handler methods out of Client's API.
+type clientHandler Client
+
+func (h *clientHandler) OnAccept() error {
+	return nil
+}
+
+func (h *clientHandler) OnOpen(conn net.Conn) {
+	h.sessions.Store(conn, newSession())
+}
+
+func (h *clientHandler) OnClose(conn net.Conn) {
+	if v, ok := h.sessions.Load(conn); ok {
+		h.sessions.Delete(conn)
+		v.(*session).close()
+	}
+}
+
+func (h *clientHandler) OnError(conn net.Conn) {
+	conn.Close()
+}
+
+func (h *clientHandler) OnReadMessage(conn net.Conn) error {
+	s, err := (*Client)(h).session(conn)
+	if err != nil {
+		return err
+	}
+
+	return s.read(conn, func(f *frame) {
+		if ch := s.done(f.id); ch != nil {
+			ch <- f
+		}
+	})
+}
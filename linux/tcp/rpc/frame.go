@@ -0,0 +1,222 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package rpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+)
+
+const (
+	kindRequest uint8 = iota + 1
+	kindResponse
+	kindError
+	kindBusy
+)
+
+const (
+	// length, id, kind, method length
+	headerSize   = 4 + 8 + 1 + 2
+	maxFrameSize = 16 << 20
+	minReadSpace = 4096
+)
+
+var (
+	// ErrFrameTooLarge means the peer sent a frame over the size limit, the
+	// connection is closed.
+	ErrFrameTooLarge = errors.New("frame too large")
+
+	// ErrInvalidFrame means the peer sent a malformed frame, the connection
+	// is closed.
+	ErrInvalidFrame = errors.New("invalid frame")
+
+	// ErrMethodTooLong means a method name doesn't fit in a frame.
+	ErrMethodTooLong = errors.New("method name too long")
+)
+
+// frame is the unit exchanged on the wire:
+//
+//	| length uint32 | id uint64 | kind uint8 | method length uint16 | method | payload |
+//
+// length counts the bytes following it, integers are big endian.
+type frame struct {
+	id      uint64
+	kind    uint8
+	method  string
+	payload []byte
+}
+
+func (f *frame) encode() ([]byte, error) {
+	if len(f.method) > 0xffff {
+		return nil, ErrMethodTooLong
+	}
+
+	size := headerSize + len(f.method) + len(f.payload)
+	if size-4 > maxFrameSize {
+		return nil, ErrFrameTooLarge
+	}
+
+	b := make([]byte, size)
+	binary.BigEndian.PutUint32(b, uint32(size-4))
+	binary.BigEndian.PutUint64(b[4:], f.id)
+	b[12] = f.kind
+	binary.BigEndian.PutUint16(b[13:], uint16(len(f.method)))
+	copy(b[headerSize:], f.method)
+	copy(b[headerSize+len(f.method):], f.payload)
+
+	return b, nil
+}
+
+// decode parses the first frame of b, n is zero while the frame is
+// incomplete. The payload is copied, b may be reused.
+func decode(b []byte) (f *frame, n int, err error) {
+	if len(b) < 4 {
+		return nil, 0, nil
+	}
+
+	length := int(binary.BigEndian.Uint32(b))
+	if length > maxFrameSize {
+		return nil, 0, ErrFrameTooLarge
+	}
+
+	if length < headerSize-4 {
+		return nil, 0, ErrInvalidFrame
+	}
+
+	if len(b) < length+4 {
+		return nil, 0, nil
+	}
+
+	mlen := int(binary.BigEndian.Uint16(b[13:]))
+	if headerSize+mlen > length+4 {
+		return nil, 0, ErrInvalidFrame
+	}
+
+	f = &frame{
+		id:      binary.BigEndian.Uint64(b[4:]),
+		kind:    b[12],
+		method:  string(b[headerSize : headerSize+mlen]),
+		payload: append([]byte(nil), b[headerSize+mlen:length+4]...),
+	}
+
+	return f, length + 4, nil
+}
+
+// session is the per connection state shared by clients and servers.
+type session struct {
+	// Serializes reads, so frames are parsed in order.
+	rmu sync.Mutex
+	buf []byte
+
+	// Calls waiting for a response, clients only.
+	mu      sync.Mutex
+	pending map[uint64]chan *frame
+	closed  bool
+}
+
+func newSession() *session {
+	return &session{
+		pending: make(map[uint64]chan *frame),
+	}
+}
+
+// read reads what's available on conn and passes every complete frame to
+// fn, in order.
+func (s *session) read(conn net.Conn, fn func(*frame)) error {
+	s.rmu.Lock()
+	defer s.rmu.Unlock()
+
+	if cap(s.buf)-len(s.buf) < minReadSpace {
+		buf := make([]byte, len(s.buf), 2*cap(s.buf)+minReadSpace)
+		copy(buf, s.buf)
+		s.buf = buf
+	}
+
+	n, err := conn.Read(s.buf[len(s.buf):cap(s.buf)])
+	s.buf = s.buf[:len(s.buf)+n]
+
+	for {
+		f, size, derr := decode(s.buf)
+		if derr != nil {
+			return derr
+		}
+
+		if size == 0 {
+			break
+		}
+
+		s.buf = s.buf[:copy(s.buf, s.buf[size:])]
+		fn(f)
+	}
+
+	return err
+}
+
+// add registers a call waiting for the response with the given id.
+func (s *session) add(id uint64) (chan *frame, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, false
+	}
+
+	ch := make(chan *frame, 1)
+	s.pending[id] = ch
+
+	return ch, true
+}
+
+// done removes a pending call, returning its channel if it was still there.
+func (s *session) done(id uint64) chan *frame {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch, ok := s.pending[id]
+	if ok {
+		delete(s.pending, id)
+	}
+
+	return ch
+}
+
+// close fails every pending call.
+func (s *session) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for id, ch := range s.pending {
+		close(ch)
+		delete(s.pending, id)
+	}
+}
@@ -0,0 +1,163 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package rpc
+
+import (
+	"net"
+	"sync"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+	"github.com/fengyfei/nuts/scheduler"
+)
+
+// Method serves a call, the returned error is sent back to the caller as a
+// ServerError.
+type Method func(conn *tcp.Conn, payload []byte) ([]byte, error)
+
+// Server dispatches calls to registered methods. It is a tcp.Handler, start
+// it with tcp.StartServer.
+type Server struct {
+	pool     *scheduler.Pool
+	sessions sync.Map
+
+	mu      sync.RWMutex
+	methods map[string]Method
+}
+
+// NewServer creates a server running methods on pool. Calls are answered
+// with ErrServerBusy while the pool's queue is full.
+func NewServer(pool *scheduler.Pool) *Server {
+	return &Server{
+		pool:    pool,
+		methods: make(map[string]Method),
+	}
+}
+
+// Register makes m callable as name, replacing any previous method.
+func (s *Server) Register(name string, m Method) {
+	s.mu.Lock()
+	s.methods[name] = m
+	s.mu.Unlock()
+}
+
+// OnAccept is the tcp.Handler implementation.
+func (s *Server) OnAccept() error {
+	return nil
+}
+
+// OnOpen is the tcp.OpenHandler implementation.
+func (s *Server) OnOpen(conn net.Conn) {
+	s.sessions.Store(conn, newSession())
+}
+
+// OnClose is the tcp.Handler implementation.
+func (s *Server) OnClose(conn net.Conn) {
+	s.sessions.Delete(conn)
+}
+
+// OnError is the tcp.Handler implementation, it closes the connection.
+func (s *Server) OnError(conn net.Conn) {
+	conn.Close()
+}
+
+// OnReadMessage is the tcp.Handler implementation. Each request runs as a
+// task of its own, so concurrent calls on a connection don't wait for each
+// other and responses may be sent out of order.
+func (s *Server) OnReadMessage(conn net.Conn) error {
+	v, ok := s.sessions.Load(conn)
+	if !ok {
+		return net.ErrClosed
+	}
+
+	var err error
+
+	rerr := v.(*session).read(conn, func(f *frame) {
+		if f.kind != kindRequest {
+			return
+		}
+
+		// Blocking here could starve the pool reads run on.
+		if s.pool.TrySchedule(scheduler.TaskFunc(func() error {
+			return s.call(conn, f)
+		})) {
+			return
+		}
+
+		b, _ := (&frame{id: f.id, kind: kindBusy}).encode()
+		if _, werr := conn.Write(b); werr != nil && err == nil {
+			err = werr
+		}
+	})
+
+	if rerr != nil {
+		return rerr
+	}
+
+	return err
+}
+
+func (s *Server) call(conn net.Conn, f *frame) error {
+	s.mu.RLock()
+	m, ok := s.methods[f.method]
+	s.mu.RUnlock()
+
+	resp := &frame{
+		id:   f.id,
+		kind: kindResponse,
+	}
+
+	if !ok {
+		resp.kind = kindError
+		resp.payload = []byte("can't find method " + f.method)
+	} else {
+		c, _ := conn.(*tcp.Conn)
+
+		payload, err := m(c, f.payload)
+		if err != nil {
+			resp.kind = kindError
+			resp.payload = []byte(err.Error())
+		} else {
+			resp.payload = payload
+		}
+	}
+
+	b, err := resp.encode()
+	if err != nil {
+		resp.kind = kindError
+		resp.payload = []byte(err.Error())
+
+		if b, err = resp.encode(); err != nil {
+			return err
+		}
+	}
+
+	_, err = conn.Write(b)
+	return err
+}
@@ -30,8 +30,12 @@
 package tcp
 
 import (
+	"context"
 	"errors"
 	"net"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fengyfei/nuts/scheduler"
@@ -41,100 +45,436 @@ import (
 var (
 	// ErrEpollCreate means couldn't create a epoll struct.
 	ErrEpollCreate = errors.New("couldn't create epoll")
+
+	// ErrNetwork means Config.Network is not supported.
+	ErrNetwork = errors.New("unsupported network")
+
+	// ErrReusePortNetwork means several reactors were asked on a network
+	// without SO_REUSEPORT.
+	ErrReusePortNetwork = errors.New("multiple reactors need a tcp network")
 )
 
 // Server represents a generic TCP server.
 type Server struct {
-	ln        net.Listener
-	poller    netpoll.Poller
-	scheduler *scheduler.Pool
-	handler   Handler
+	conf     *Config
+	reactors []*reactor
+	handler  Handler
+	wheel    *timingWheel
+	proxies  []*net.IPNet
+	limits   *limiter
+	groups   groups
+	released func(*Conn)
+	closed   int32
+	retired  sync.Once
+
+	// Guards the listeners, and copies of them handed to a child process,
+	// see PassListeners.
+	mu        sync.Mutex
+	listeners []*listener
+	exported  []*os.File
 }
 
 // StartServer starts a TCP server based on configuration.
 func StartServer(c *Config, h Handler, pool *scheduler.Pool) (*Server, error) {
-	var (
-		p netpoll.Poller
-	)
-	ln, err := net.Listen("tcp", c.Address)
-	if err != nil {
+	if err := c.validate(); err != nil {
 		return nil, err
 	}
 
-	p, err = netpoll.New(nil)
-	if err != nil {
-		ln.Close()
-		return nil, ErrEpollCreate
+	s := &Server{
+		conf:    c,
+		handler: h,
 	}
 
-	s := &Server{
-		ln:        ln,
-		poller:    p,
-		scheduler: pool,
-		handler:   h,
+	if c.Proxy != nil {
+		s.proxies, _ = c.Proxy.networks()
 	}
 
-	if err = s.accept(); err != nil {
-		ln.Close()
-		return nil, err
+	if c.RateLimit != nil {
+		s.limits = newLimiter(c.RateLimit.GlobalBytesPerSecond, c.RateLimit.GlobalMessagesPerSecond)
+	}
+
+	if c.idleEnabled() {
+		s.wheel = newTimingWheel(c.idleTick(), s.checkIdle)
+	}
+
+	confs := c.listeners()
+
+	n := c.Reactors
+	if n < 1 {
+		n = 1
+	}
+
+	// Listeners handed over by a parent process replace the reactors.
+	inherited := inheritedListeners(confs[0].network(), confs[0].Address)
+	if len(inherited) > 0 {
+		n = len(inherited)
+	}
+
+	for i := 0; i < n; i++ {
+		if len(c.Pools) > 0 {
+			pool = c.Pools[i%len(c.Pools)]
+		}
+
+		id := i
+		r, err := newReactor(id, pool, func(err error) {
+			s.pollerError(id, err)
+		})
+		if err != nil {
+			for _, ln := range inherited {
+				ln.Close()
+			}
+			s.retire()
+			return nil, err
+		}
+		s.reactors = append(s.reactors, r)
+	}
+
+	for i := range confs {
+		if i > 0 {
+			inherited = inheritedListeners(confs[i].network(), confs[i].Address)
+		}
+
+		if err := s.listen(&confs[i], inherited); err != nil {
+			s.stop()
+			s.retire()
+			return nil, err
+		}
 	}
 
 	return s, nil
 }
 
-func (s *Server) accept() error {
-	// error channel
-	acceptError := make(chan error, 1)
+// Close stops accepting new connections. Established connections are not
+// closed, the pollers serving them stop once they are all gone.
+func (s *Server) Close() error {
+	if !atomic.CompareAndSwapInt32(&s.closed, 0, 1) {
+		return nil
+	}
+
+	err := s.stop()
+	s.shutdown()
+
+	if s.active() == 0 {
+		s.retire()
+	}
+
+	return err
+}
 
-	// read event handler
-	handler := func(conn net.Conn) {
-		desc := netpoll.Must(netpoll.HandleRead(conn))
+// retire stops the timing wheel and the pollers, the latter on another
+// goroutine since connections may close from their callbacks.
+func (s *Server) retire() {
+	s.retired.Do(func() {
+		if s.wheel != nil {
+			s.wheel.stop()
+		}
 
-		s.poller.Start(desc, func(e netpoll.Event) {
-			// Client connection closed.
-			if e&(netpoll.EventReadHup|netpoll.EventHup) != 0 {
-				s.handler.OnClose(conn)
-				return
+		go func() {
+			for _, r := range s.reactors {
+				r.close()
 			}
+		}()
+	})
+}
 
-			// Read from connection
-			s.scheduler.Schedule(scheduler.TaskFunc(func() error {
-				if err := s.handler.OnReadMessage(conn); err != nil {
-					s.poller.Stop(desc)
-					s.handler.OnError(conn)
-					return err
-				}
-				return nil
-			}))
-		})
+func (s *Server) stop() error {
+	var err error
+
+	s.mu.Lock()
+	for _, l := range s.listeners {
+		if e := l.close(); e != nil && err == nil {
+			err = e
+		}
 	}
 
-	// accept event handler
-	acceptor := func() error {
-		conn, err := s.ln.Accept()
+	for _, f := range s.exported {
+		f.Close()
+	}
+	s.exported = nil
+	s.mu.Unlock()
 
-		if err != nil {
-			acceptError <- err
+	return err
+}
+
+// Shutdown stops accepting new connections, then waits until the
+// established ones are closed or ctx is done. Combined with PassListeners,
+// it lets a process hand its work over to a new one.
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.Close()
+
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+
+	for s.active() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
 		}
+	}
+
+	return err
+}
 
-		handler(conn)
+func (s *Server) active() int64 {
+	var n int64
 
+	for _, r := range s.reactors {
+		n += atomic.LoadInt64(&r.active)
+	}
+
+	return n
+}
+
+// Addr returns the address of the first listener, nil if there is none.
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.listeners) == 0 {
 		return nil
 	}
 
-	acceptDesc := netpoll.Must(netpoll.HandleListener(s.ln, netpoll.EventRead|netpoll.EventOneShot))
-	s.poller.Start(acceptDesc, func(e netpoll.Event) {
-		err := s.scheduler.ScheduleWithTimeout(2*time.Microsecond, scheduler.TaskFunc(acceptor))
-		if err == nil {
-			err = <-acceptError
+	return s.listeners[0].ln.Addr()
+}
+
+// Stats returns the counters of every reactor, in the order they were
+// started.
+func (s *Server) Stats() []ReactorStats {
+	stats := make([]ReactorStats, len(s.reactors))
+
+	for i, r := range s.reactors {
+		stats[i] = r.stats()
+	}
+
+	return stats
+}
+
+// release undoes everything the server did for c, it's called once by
+// Conn.Close.
+func (s *Server) release(c *Conn) {
+	if s.wheel != nil {
+		s.wheel.remove(c)
+	}
+
+	// The poller may report a hang-up before Start returned.
+	c.pollMu.Lock()
+	c.reactor.poller.Stop(c.desc)
+	c.desc.Close()
+	c.pollMu.Unlock()
+
+	s.groups.leaveAll(c)
+	atomic.AddInt64(&c.reactor.active, -1)
+	s.handler.OnClose(c)
+
+	if s.released != nil {
+		s.released(c)
+	}
+
+	// The last connection of a closed server.
+	if atomic.LoadInt32(&s.closed) != 0 && s.active() == 0 {
+		s.retire()
+	}
+}
+
+// checkIdle is called by the timing wheel when c may have been idle for too
+// long, it fires the expired timeouts and re-arms the wheel.
+func (s *Server) checkIdle(c *Conn) {
+	if c.isClosed() {
+		return
+	}
+
+	now := time.Now()
+
+	if s.conf.MaxLifetime > 0 && now.Sub(c.created) >= s.conf.MaxLifetime {
+		s.fireIdle(c, LifetimeExpired)
+		return
+	}
+
+	next := s.conf.MaxLifetime - now.Sub(c.created)
+
+	check := func(timeout time.Duration, last, notified *int64, state IdleState) {
+		if timeout <= 0 {
+			return
 		}
 
-		if err != nil {
-			time.Sleep(5 * time.Microsecond)
+		since := atomic.LoadInt64(last)
+		if n := atomic.LoadInt64(notified); n > since {
+			since = n
+		}
+
+		remain := timeout - now.Sub(time.Unix(0, since))
+		if remain <= 0 {
+			atomic.StoreInt64(notified, now.UnixNano())
+			s.fireIdle(c, state)
+			remain = timeout
+		}
+
+		if next <= 0 || remain < next {
+			next = remain
+		}
+	}
+
+	check(s.conf.ReadIdleTimeout, &c.lastRead, &c.readNotified, ReadIdle)
+	check(s.conf.WriteIdleTimeout, &c.lastWrite, &c.writeNotified, WriteIdle)
+
+	s.wheel.schedule(c, next)
+}
+
+// fireIdle runs the idle callback on the pool, never on the wheel. The
+// wheel serves every connection, so it doesn't wait for a full queue.
+func (s *Server) fireIdle(c *Conn, state IdleState) {
+	task := scheduler.TaskFunc(func() error {
+		if h, ok := s.handler.(IdleHandler); ok {
+			h.OnIdle(c, state)
+		} else if state != WriteIdle {
+			c.Close()
 		}
 
-		s.poller.Resume(acceptDesc)
+		if state == LifetimeExpired {
+			c.Close()
+		}
+
+		return nil
 	})
 
-	return nil
+	if !c.reactor.scheduler.TrySchedule(task) {
+		go task.Do()
+	}
+}
+
+// serve registers an established connection with the poller, raw is the
+// socket polled for events and conn the one handed to the Handler. l is
+// nil for client connections. It fails when the descriptor of raw can't be
+// duplicated for the poller, as when the process is out of descriptors,
+// the caller closes the connection then.
+func (s *Server) serve(r *reactor, l *listener, raw, conn net.Conn, hdr *ProxyHeader) (*Conn, error) {
+	desc, err := netpoll.HandleRead(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newConn(s, r, l, raw, conn, desc)
+	c.proxy = hdr
+	s.accepted(c)
+
+	if h, ok := s.handler.(OpenHandler); ok {
+		h.OnOpen(c)
+	}
+
+	atomic.AddUint64(&r.accepted, 1)
+	atomic.AddInt64(&r.active, 1)
+
+	if s.wheel != nil {
+		s.checkIdle(c)
+	}
+
+	// One read task per connection at most, it keeps calling the Handler
+	// while data is available. Edge-triggered events arriving meanwhile are
+	// coalesced, so no worker ever blocks on a drained socket.
+	var (
+		read    scheduler.TaskFunc
+		onEvent func(netpoll.Event)
+	)
+
+	read = func() error {
+		for {
+			// No callback comes after OnClose.
+			if c.isClosed() {
+				return nil
+			}
+
+			switch c.probe() {
+			case probeEmpty:
+				atomic.StoreInt32(&c.reading, 0)
+
+				// Data may have come after the check, its event was dropped.
+				if c.probe() == probeEmpty || !atomic.CompareAndSwapInt32(&c.reading, 0, 1) {
+					return nil
+				}
+				continue
+			case probeClosed:
+				// The peer is gone, after everything it sent was handled.
+				c.Close()
+				return nil
+			}
+
+			var err error
+			if h, ok := s.handler.(BufferHandler); ok {
+				err = c.readBuffers(h)
+			} else if err = c.admit(); err == nil {
+				err = s.handler.OnReadMessage(c)
+			}
+
+			if err == errLimited {
+				atomic.AddUint64(&r.limited, 1)
+
+				switch s.conf.RateLimit.Action {
+				case Disconnect:
+					c.Close()
+					return nil
+				case Drop:
+					err = c.discard()
+				default:
+					// The poller stops watching the connection while paused,
+					// TCP flow control holds the peer back. Reading stays
+					// flagged, so that nothing else schedules a read.
+					c.stopPolling()
+					time.AfterFunc(c.limitDelay(), func() {
+						if err := c.resumePolling(onEvent); err != nil {
+							c.Close()
+							return
+						}
+
+						atomic.AddUint64(&r.reads, 1)
+						r.scheduler.Schedule(read)
+					})
+					return nil
+				}
+			}
+
+			if err != nil {
+				s.readError(c, err)
+				c.stopPolling()
+				s.handler.OnError(c)
+				return err
+			}
+		}
+	}
+
+	schedule := func() {
+		if atomic.CompareAndSwapInt32(&c.reading, 0, 1) {
+			atomic.AddUint64(&r.reads, 1)
+			r.scheduler.Schedule(read)
+		}
+	}
+
+	onEvent = func(e netpoll.Event) {
+		// Nothing can be read after a reset. After a half-close, the read
+		// task hands the data sent before it to the Handler, then closes.
+		if e&(netpoll.EventHup|netpoll.EventErr) != 0 {
+			c.Close()
+			return
+		}
+
+		// Read from connection
+		schedule()
+	}
+
+	// The server may have retired its pollers meanwhile, when closed
+	// during a handshake.
+	c.pollMu.Lock()
+	err = r.poller.Start(desc, onEvent)
+	c.pollMu.Unlock()
+
+	if err != nil {
+		c.Close()
+		return c, nil
+	}
+
+	if c.buffered() {
+		schedule()
+	}
+
+	return c, nil
 }
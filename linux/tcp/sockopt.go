@@ -0,0 +1,261 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package tcp
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// SocketOptions tunes the listening socket and every accepted connection.
+// Zero values keep the system defaults.
+type SocketOptions struct {
+	// Nagle turns Nagle's algorithm back on, Go sets TCP_NODELAY by default.
+	Nagle bool
+
+	// KeepAlive is the idle time before the first keep-alive probe,
+	// negative disables SO_KEEPALIVE. KeepAliveInterval and KeepAliveCount
+	// control the probes that follow.
+	KeepAlive         time.Duration
+	KeepAliveInterval time.Duration
+	KeepAliveCount    int
+
+	// ReadBuffer and WriteBuffer set SO_RCVBUF and SO_SNDBUF in bytes.
+	ReadBuffer  int
+	WriteBuffer int
+
+	// Linger sets SO_LINGER, negative resets connections on close instead
+	// of sending what is left.
+	Linger time.Duration
+
+	// FastOpen is the TCP_FASTOPEN queue length of the listener.
+	FastOpen int
+
+	// DeferAccept sets TCP_DEFER_ACCEPT, a connection is only accepted once
+	// data arrived or DeferAccept elapsed.
+	DeferAccept time.Duration
+
+	// Backlog is the length of the accept queue, by default the one of
+	// net.Listen.
+	Backlog int
+}
+
+// sockopt is an integer socket option.
+type sockopt struct {
+	level, name, value int
+}
+
+// OptionError reports an invalid socket option.
+type OptionError struct {
+	Option string
+	Value  interface{}
+}
+
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("invalid socket option %s: %v", e.Option, e.Value)
+}
+
+func (o *SocketOptions) validate(network string) error {
+	for _, v := range []struct {
+		option string
+		value  int64
+	}{
+		{"KeepAliveInterval", int64(o.KeepAliveInterval)},
+		{"KeepAliveCount", int64(o.KeepAliveCount)},
+		{"ReadBuffer", int64(o.ReadBuffer)},
+		{"WriteBuffer", int64(o.WriteBuffer)},
+		{"FastOpen", int64(o.FastOpen)},
+		{"DeferAccept", int64(o.DeferAccept)},
+		{"Backlog", int64(o.Backlog)},
+	} {
+		if v.value < 0 {
+			return &OptionError{Option: v.option, Value: v.value}
+		}
+	}
+
+	// Keep-alive settings are in whole seconds.
+	for _, v := range []struct {
+		option string
+		value  time.Duration
+	}{
+		{"KeepAlive", o.KeepAlive},
+		{"KeepAliveInterval", o.KeepAliveInterval},
+		{"Linger", o.Linger},
+		{"DeferAccept", o.DeferAccept},
+	} {
+		if v.value > 0 && v.value < time.Second {
+			return &OptionError{Option: v.option, Value: v.value}
+		}
+	}
+
+	if network != "unix" {
+		return nil
+	}
+
+	switch {
+	case o.Nagle:
+		return &OptionError{Option: "Nagle", Value: network}
+	case o.KeepAlive != 0 || o.KeepAliveInterval != 0 || o.KeepAliveCount != 0:
+		return &OptionError{Option: "KeepAlive", Value: network}
+	case o.FastOpen != 0:
+		return &OptionError{Option: "FastOpen", Value: network}
+	case o.DeferAccept != 0:
+		return &OptionError{Option: "DeferAccept", Value: network}
+	}
+
+	return nil
+}
+
+// listener sets the options that must be in place before the socket
+// listens. Buffer sizes are inherited by accepted connections, and only
+// take part in window scaling when set that early.
+func (o *SocketOptions) listener(fd int) error {
+	opts := []sockopt{
+		{unix.SOL_SOCKET, unix.SO_RCVBUF, o.ReadBuffer},
+		{unix.SOL_SOCKET, unix.SO_SNDBUF, o.WriteBuffer},
+		{unix.IPPROTO_TCP, unix.TCP_FASTOPEN, o.FastOpen},
+		{unix.IPPROTO_TCP, unix.TCP_DEFER_ACCEPT, int(o.DeferAccept / time.Second)},
+	}
+
+	for _, opt := range opts {
+		if opt.value == 0 {
+			continue
+		}
+
+		if err := unix.SetsockoptInt(fd, opt.level, opt.name, opt.value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// backlog listens again on a listening socket, which only changes the
+// length of its accept queue.
+func (o *SocketOptions) backlog(ln net.Listener) error {
+	if o.Backlog == 0 {
+		return nil
+	}
+
+	sc, ok := ln.(syscall.Conn)
+	if !ok {
+		return ErrNotSyscallConn
+	}
+
+	return control(sc, func(fd int) error {
+		return unix.Listen(fd, o.Backlog)
+	})
+}
+
+// conn applies the per connection options to an accepted connection.
+func (o *SocketOptions) conn(conn net.Conn, network string) error {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return ErrNotSyscallConn
+	}
+
+	return control(sc, func(fd int) error {
+		var opts []sockopt
+
+		add := func(level, name, value int) {
+			opts = append(opts, sockopt{level, name, value})
+		}
+
+		if o.ReadBuffer > 0 {
+			add(unix.SOL_SOCKET, unix.SO_RCVBUF, o.ReadBuffer)
+		}
+
+		if o.WriteBuffer > 0 {
+			add(unix.SOL_SOCKET, unix.SO_SNDBUF, o.WriteBuffer)
+		}
+
+		if network != "unix" {
+			if o.Nagle {
+				add(unix.IPPROTO_TCP, unix.TCP_NODELAY, 0)
+			}
+
+			switch {
+			case o.KeepAlive < 0:
+				add(unix.SOL_SOCKET, unix.SO_KEEPALIVE, 0)
+			case o.KeepAlive > 0 || o.KeepAliveInterval > 0 || o.KeepAliveCount > 0:
+				add(unix.SOL_SOCKET, unix.SO_KEEPALIVE, 1)
+
+				if o.KeepAlive > 0 {
+					add(unix.IPPROTO_TCP, unix.TCP_KEEPIDLE, int(o.KeepAlive/time.Second))
+				}
+
+				if o.KeepAliveInterval > 0 {
+					add(unix.IPPROTO_TCP, unix.TCP_KEEPINTVL, int(o.KeepAliveInterval/time.Second))
+				}
+
+				if o.KeepAliveCount > 0 {
+					add(unix.IPPROTO_TCP, unix.TCP_KEEPCNT, o.KeepAliveCount)
+				}
+			}
+		}
+
+		for _, opt := range opts {
+			if err := unix.SetsockoptInt(fd, opt.level, opt.name, opt.value); err != nil {
+				return err
+			}
+		}
+
+		if o.Linger == 0 {
+			return nil
+		}
+
+		l := &unix.Linger{Onoff: 1}
+		if o.Linger > 0 {
+			l.Linger = int32(o.Linger / time.Second)
+		}
+
+		return unix.SetsockoptLinger(fd, unix.SOL_SOCKET, unix.SO_LINGER, l)
+	})
+}
+
+func control(sc syscall.Conn, fn func(fd int) error) error {
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var ferr error
+	if err = rc.Control(func(fd uintptr) {
+		ferr = fn(int(fd))
+	}); err != nil {
+		return err
+	}
+
+	return ferr
+}
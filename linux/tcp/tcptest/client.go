@@ -0,0 +1,196 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package tcptest
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// DefaultTimeout bounds every step of a Client.
+const DefaultTimeout = 5 * time.Second
+
+// Client is a scripted peer, its steps fail the test when they don't go
+// as expected. Steps return the client, so that they can be chained.
+type Client struct {
+	t      testing.TB
+	server *Server
+	conn   *net.TCPConn
+
+	// Timeout bounds each step, DefaultTimeout by default.
+	Timeout time.Duration
+}
+
+// Dial connects a client to the server.
+func (s *Server) Dial(t testing.TB) *Client {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", s.Addr)
+	if err != nil {
+		t.Fatalf("tcptest: dial: %v", err)
+	}
+
+	return &Client{
+		t:       t,
+		server:  s,
+		conn:    conn.(*net.TCPConn),
+		Timeout: DefaultTimeout,
+	}
+}
+
+// Addr returns the address of the client, which is the key of its events
+// in the Recorder.
+func (c *Client) Addr() string {
+	return c.conn.LocalAddr().String()
+}
+
+// Conn returns the client side of the connection.
+func (c *Client) Conn() *net.TCPConn {
+	return c.conn
+}
+
+// Send writes b.
+func (c *Client) Send(b []byte) *Client {
+	c.t.Helper()
+
+	c.conn.SetWriteDeadline(time.Now().Add(c.Timeout))
+	if _, err := c.conn.Write(b); err != nil {
+		c.t.Fatalf("tcptest: send: %v", err)
+	}
+
+	return c
+}
+
+// SendString writes s.
+func (c *Client) SendString(s string) *Client {
+	c.t.Helper()
+
+	return c.Send([]byte(s))
+}
+
+// Expect reads len(want) bytes and checks they are want.
+func (c *Client) Expect(want []byte) *Client {
+	c.t.Helper()
+
+	got := make([]byte, len(want))
+
+	c.conn.SetReadDeadline(time.Now().Add(c.Timeout))
+	if n, err := io.ReadFull(c.conn, got); err != nil {
+		c.t.Fatalf("tcptest: expected %q, read %q: %v", want, got[:n], err)
+	}
+
+	if !bytes.Equal(got, want) {
+		c.t.Fatalf("tcptest: expected %q, read %q", want, got)
+	}
+
+	return c
+}
+
+// ExpectString reads len(s) bytes and checks they are s.
+func (c *Client) ExpectString(s string) *Client {
+	c.t.Helper()
+
+	return c.Expect([]byte(s))
+}
+
+// ExpectEOF checks the server closed the connection, without sending
+// anything more.
+func (c *Client) ExpectEOF() *Client {
+	c.t.Helper()
+
+	var b [1]byte
+
+	c.conn.SetReadDeadline(time.Now().Add(c.Timeout))
+	if n, err := c.conn.Read(b[:]); err != io.EOF {
+		c.t.Fatalf("tcptest: expected EOF, read %q: %v", b[:n], err)
+	}
+
+	return c
+}
+
+// CloseWrite half-closes the connection, the server reads EOF and may
+// still write.
+func (c *Client) CloseWrite() *Client {
+	c.t.Helper()
+
+	if err := c.conn.CloseWrite(); err != nil {
+		c.t.Fatalf("tcptest: close write: %v", err)
+	}
+
+	return c
+}
+
+// Reset aborts the connection, the server gets a RST instead of a FIN.
+func (c *Client) Reset() {
+	c.t.Helper()
+
+	c.conn.SetLinger(0)
+	if err := c.conn.Close(); err != nil {
+		c.t.Fatalf("tcptest: reset: %v", err)
+	}
+}
+
+// Close closes the connection.
+func (c *Client) Close() {
+	c.conn.Close()
+}
+
+// WaitFor waits until the server got a callback of kind for this client.
+func (c *Client) WaitFor(kind EventKind) *Client {
+	c.t.Helper()
+
+	if !c.server.Recorder.Wait(c.Addr(), kind, c.Timeout) {
+		c.t.Fatalf("tcptest: no %v after %v, got %v", kind, c.Timeout, c.server.Recorder.Kinds(c.Addr()))
+	}
+
+	return c
+}
+
+// ExpectEvents checks the callbacks the server got for this client so far,
+// consecutive reads counting as one.
+func (c *Client) ExpectEvents(want ...EventKind) *Client {
+	c.t.Helper()
+
+	got := c.server.Recorder.Kinds(c.Addr())
+	if len(got) != len(want) {
+		c.t.Fatalf("tcptest: expected callbacks %v, got %v", want, got)
+	}
+
+	for i := range got {
+		if got[i] != want[i] {
+			c.t.Fatalf("tcptest: expected callbacks %v, got %v", want, got)
+		}
+	}
+
+	return c
+}
@@ -0,0 +1,257 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package tcptest
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+)
+
+// EventKind is a Handler callback.
+type EventKind int
+
+const (
+	// Read is OnReadMessage or OnReadBuffer.
+	Read EventKind = iota + 1
+	Error
+	Close
+	Idle
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case Read:
+		return "Read"
+	case Error:
+		return "Error"
+	case Close:
+		return "Close"
+	case Idle:
+		return "Idle"
+	}
+
+	return "Unknown"
+}
+
+// Event is a recorded callback.
+type Event struct {
+	Kind EventKind
+
+	// Err is what a Read returned.
+	Err error
+
+	// State is the state passed to OnIdle.
+	State tcp.IdleState
+}
+
+// Recorder records the callbacks of every connection, keyed by the
+// address of the peer.
+type Recorder struct {
+	mu      sync.Mutex
+	events  map[string][]Event
+	changed chan struct{}
+}
+
+// NewRecorder creates an empty recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{
+		events:  make(map[string][]Event),
+		changed: make(chan struct{}),
+	}
+}
+
+// Events returns the callbacks of the connection from addr, in order.
+func (r *Recorder) Events(addr string) []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Event(nil), r.events[addr]...)
+}
+
+// Kinds returns the kinds of the callbacks of the connection from addr,
+// consecutive reads being folded into one.
+func (r *Recorder) Kinds(addr string) []EventKind {
+	var kinds []EventKind
+
+	for _, e := range r.Events(addr) {
+		if e.Kind == Read && len(kinds) > 0 && kinds[len(kinds)-1] == Read {
+			continue
+		}
+		kinds = append(kinds, e.Kind)
+	}
+
+	return kinds
+}
+
+// Wait waits for the connection from addr to get a callback of kind, it
+// reports whether one came within timeout.
+func (r *Recorder) Wait(addr string, kind EventKind, timeout time.Duration) bool {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		r.mu.Lock()
+		changed := r.changed
+		for _, e := range r.events[addr] {
+			if e.Kind == kind {
+				r.mu.Unlock()
+				return true
+			}
+		}
+		r.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-deadline.C:
+			return false
+		}
+	}
+}
+
+// record appends e and returns its index.
+func (r *Recorder) record(conn net.Conn, e Event) int {
+	addr := conn.RemoteAddr().String()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events[addr] = append(r.events[addr], e)
+	r.notify()
+
+	return len(r.events[addr]) - 1
+}
+
+// returned sets what the Read at index i returned.
+func (r *Recorder) returned(conn net.Conn, i int, err error) {
+	addr := conn.RemoteAddr().String()
+
+	r.mu.Lock()
+	r.events[addr][i].Err = err
+	r.notify()
+	r.mu.Unlock()
+}
+
+// notify needs mu held.
+func (r *Recorder) notify() {
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// Wrap returns a handler recording the callbacks before passing them on to
+// h. It implements the same optional interfaces as h, so that the server
+// behaves the same.
+func (r *Recorder) Wrap(h tcp.Handler) tcp.Handler {
+	base := &recorded{r: r, h: h}
+
+	bh, buffered := h.(tcp.BufferHandler)
+	ih, idle := h.(tcp.IdleHandler)
+
+	switch {
+	case buffered && idle:
+		return &struct {
+			*recorded
+			*recordedBuffer
+			*recordedIdle
+		}{base, &recordedBuffer{r, bh}, &recordedIdle{r, ih}}
+	case buffered:
+		return &struct {
+			*recorded
+			*recordedBuffer
+		}{base, &recordedBuffer{r, bh}}
+	case idle:
+		return &struct {
+			*recorded
+			*recordedIdle
+		}{base, &recordedIdle{r, ih}}
+	}
+
+	return base
+}
+
+type recorded struct {
+	r *Recorder
+	h tcp.Handler
+}
+
+func (h *recorded) OnAccept() error {
+	return h.h.OnAccept()
+}
+
+// OnOpen isn't recorded, it's passed on when h wants it.
+func (h *recorded) OnOpen(conn net.Conn) {
+	if oh, ok := h.h.(tcp.OpenHandler); ok {
+		oh.OnOpen(conn)
+	}
+}
+
+func (h *recorded) OnClose(conn net.Conn) {
+	h.r.record(conn, Event{Kind: Close})
+	h.h.OnClose(conn)
+}
+
+func (h *recorded) OnError(conn net.Conn) {
+	h.r.record(conn, Event{Kind: Error})
+	h.h.OnError(conn)
+}
+
+// Reads are recorded before calling on, a Close from within comes after.
+func (h *recorded) OnReadMessage(conn net.Conn) error {
+	i := h.r.record(conn, Event{Kind: Read})
+	err := h.h.OnReadMessage(conn)
+	h.r.returned(conn, i, err)
+
+	return err
+}
+
+type recordedBuffer struct {
+	r  *Recorder
+	bh tcp.BufferHandler
+}
+
+func (h *recordedBuffer) OnReadBuffer(conn net.Conn, b *tcp.Buffer) error {
+	i := h.r.record(conn, Event{Kind: Read})
+	err := h.bh.OnReadBuffer(conn, b)
+	h.r.returned(conn, i, err)
+
+	return err
+}
+
+type recordedIdle struct {
+	r  *Recorder
+	ih tcp.IdleHandler
+}
+
+func (h *recordedIdle) OnIdle(conn net.Conn, state tcp.IdleState) {
+	h.r.record(conn, Event{Kind: Idle, State: state})
+	h.ih.OnIdle(conn, state)
+}
@@ -0,0 +1,156 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package tcptest
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+	"github.com/fengyfei/nuts/scheduler"
+)
+
+// Server is a tcp.Server listening on an ephemeral loopback port, with a
+// Recorder in front of its handler.
+type Server struct {
+	*tcp.Server
+
+	// Addr is the address to dial, as host:port.
+	Addr     string
+	Recorder *Recorder
+
+	// Descriptors open once the server started.
+	fds int
+}
+
+// NewServer starts a server with h, Echo if nil. It panics if it can't.
+func NewServer(h tcp.Handler) *Server {
+	return NewServerConfig(&tcp.Config{}, h)
+}
+
+// NewServerConfig starts a server with a copy of c, listening on an
+// ephemeral loopback port unless c.Address is set.
+func NewServerConfig(c *tcp.Config, h tcp.Handler) *Server {
+	conf := *c
+	if conf.Address == "" {
+		conf.Address = "127.0.0.1:0"
+	}
+
+	if h == nil {
+		h = Echo{}
+	}
+
+	rec := NewRecorder()
+
+	s, err := tcp.StartServer(&conf, rec.Wrap(h), scheduler.New(64, 4))
+	if err != nil {
+		panic(fmt.Sprintf("tcptest: failed to start server: %v", err))
+	}
+
+	return &Server{
+		Server:   s,
+		Addr:     s.Addr().String(),
+		Recorder: rec,
+		fds:      openFiles(),
+	}
+}
+
+// CheckLeaks waits until no connection is active anymore and the process
+// has no more descriptors open than when the server started, clients of
+// the same process included. It fails after timeout.
+func (s *Server) CheckLeaks(timeout time.Duration) error {
+	var active int64
+
+	deadline := time.Now().Add(timeout)
+	for {
+		active = 0
+		for _, st := range s.Stats() {
+			active += st.Active
+		}
+
+		fds := openFiles()
+		if active == 0 && fds <= s.fds {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("tcptest: %d connections active, %d descriptors open, %d at start", active, fds, s.fds)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func openFiles() int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return 0
+	}
+
+	// ReadDir's own descriptor is listed as well.
+	return len(entries) - 1
+}
+
+// Echo writes back whatever it reads, and closes connections on error.
+type Echo struct{}
+
+// OnAccept is the tcp.Handler implementation.
+func (Echo) OnAccept() error {
+	return nil
+}
+
+// OnClose is the tcp.Handler implementation.
+func (Echo) OnClose(net.Conn) {}
+
+// OnError is the tcp.Handler implementation.
+func (Echo) OnError(conn net.Conn) {
+	conn.Close()
+}
+
+// OnReadMessage is the tcp.Handler implementation.
+func (Echo) OnReadMessage(conn net.Conn) error {
+	b := make([]byte, 4096)
+
+	n, err := conn.Read(b)
+	if n > 0 {
+		if _, werr := conn.Write(b[:n]); werr != nil {
+			return werr
+		}
+	}
+
+	if err == nil && n == 0 {
+		err = io.EOF
+	}
+
+	return err
+}
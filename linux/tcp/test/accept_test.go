@@ -0,0 +1,290 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package test
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+	"github.com/fengyfei/nuts/linux/tcp/tcptest"
+	"golang.org/x/sys/unix"
+)
+
+// exhaust connects n sockets to addr once the process is out of
+// descriptors, so that the server can't accept them. The sockets are opened
+// beforehand, it returns them along with a function restoring the limit.
+func exhaust(t *testing.T, addr string, n int) ([]int, func()) {
+	tcpAddr, err := net.ResolveTCPAddr("tcp4", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sa := &unix.SockaddrInet4{Port: tcpAddr.Port}
+	copy(sa.Addr[:], tcpAddr.IP.To4())
+
+	socks := make([]int, n)
+	for i := range socks {
+		if socks[i], err = unix.Socket(unix.AF_INET, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		// Reads fail with EAGAIN instead of hanging.
+		tv := unix.NsecToTimeval(int64(5 * time.Second))
+		unix.SetsockoptTimeval(socks[i], unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv)
+	}
+
+	var limit unix.Rlimit
+	if err = unix.Getrlimit(unix.RLIMIT_NOFILE, &limit); err != nil {
+		t.Skip(err)
+	}
+
+	// Earlier tests release descriptors in the background, one freed
+	// afterwards would be accepted on.
+	deadline := time.Now().Add(5 * time.Second)
+	for n := -1; n != descriptors() && time.Now().Before(deadline); {
+		n = descriptors()
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	low := limit
+	low.Cur = uint64(len(entries))
+	if err = unix.Setrlimit(unix.RLIMIT_NOFILE, &low); err != nil {
+		t.Skip(err)
+	}
+
+	// Descriptors below the limit may still be free, fill them.
+	var fillers []int
+	for {
+		fd, err := unix.Open("/dev/null", unix.O_RDONLY|unix.O_CLOEXEC, 0)
+		if err != nil {
+			break
+		}
+		fillers = append(fillers, fd)
+	}
+
+	var once sync.Once
+	restore := func() {
+		once.Do(func() {
+			unix.Setrlimit(unix.RLIMIT_NOFILE, &limit)
+			for _, fd := range fillers {
+				unix.Close(fd)
+			}
+		})
+	}
+
+	for _, fd := range socks {
+		if err = unix.Connect(fd, sa); err != nil {
+			restore()
+			t.Fatal(err)
+		}
+	}
+
+	return socks, restore
+}
+
+func closeAll(fds []int) {
+	for _, fd := range fds {
+		unix.Close(fd)
+	}
+}
+
+// logged collects the messages of a Logger, with their error.
+type logged struct {
+	mu   sync.Mutex
+	errs map[string]error
+}
+
+func (l *logged) Log(msg string, keyvals ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.errs == nil {
+		l.errs = make(map[string]error)
+	}
+
+	l.errs[msg] = nil
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if err, ok := keyvals[i+1].(error); ok && keyvals[i] == "err" {
+			l.errs[msg] = err
+		}
+	}
+}
+
+func (l *logged) wait(t *testing.T, msg string) error {
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		l.mu.Lock()
+		err, ok := l.errs[msg]
+		l.mu.Unlock()
+
+		if ok {
+			return err
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("%q never logged", msg)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAcceptErrorLogged(t *testing.T) {
+	var (
+		logger   logged
+		reported = make(chan error, 16)
+	)
+
+	s := tcptest.NewServerConfig(&tcp.Config{
+		Logger: &logger,
+		OnServerError: func(err error) {
+			select {
+			case reported <- err:
+			default:
+			}
+		},
+	}, nil)
+	defer s.Close()
+
+	socks, restore := exhaust(t, s.Addr, 1)
+	defer closeAll(socks)
+
+	err := logger.wait(t, "accept error")
+	restore()
+
+	if !errors.Is(err, unix.EMFILE) {
+		t.Fatalf("logged %v", err)
+	}
+
+	if err = <-reported; !errors.Is(err, unix.EMFILE) {
+		t.Fatalf("reported %v", err)
+	}
+}
+
+func TestAcceptShed(t *testing.T) {
+	var failures int64
+
+	s := tcptest.NewServerConfig(&tcp.Config{
+		OnServerError: func(err error) {
+			if errors.Is(err, unix.EMFILE) {
+				atomic.AddInt64(&failures, 1)
+			}
+		},
+	}, nil)
+	defer s.Close()
+
+	socks, restore := exhaust(t, s.Addr, 8)
+	defer restore()
+	defer closeAll(socks)
+
+	// The connections are dropped instead of left hanging in the backlog.
+	for _, fd := range socks {
+		b := make([]byte, 4)
+
+		unix.Write(fd, []byte("ping"))
+		n, err := unix.Read(fd, b)
+
+		switch {
+		case err == unix.EAGAIN:
+			t.Fatal("connection left hanging")
+		case n > 0:
+			t.Fatal("connection served")
+		}
+	}
+
+	// The failure is reported once the connections are shed.
+	for deadline := time.Now().Add(5 * time.Second); atomic.LoadInt64(&failures) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("no accept failure reported")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Accepting resumes once descriptors are available again.
+	restore()
+
+	c := s.Dial(t)
+	c.SendString("ping").ExpectString("ping").Close()
+}
+
+func TestServeOutOfDescriptors(t *testing.T) {
+	reported := make(chan error, 16)
+
+	s := tcptest.NewServerConfig(&tcp.Config{
+		Proxy: &tcp.ProxyConfig{Trusted: []string{"127.0.0.1"}},
+		OnServerError: func(err error) {
+			select {
+			case reported <- err:
+			default:
+			}
+		},
+	}, nil)
+	defer s.Close()
+
+	// Accepted, the server waits for the PROXY header.
+	conn, err := net.Dial("tcp", s.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_, restore := exhaust(t, s.Addr, 0)
+	defer restore()
+
+	// Polling the connection needs another descriptor, it's refused.
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	conn.Write([]byte("PROXY TCP4 10.0.0.1 10.0.0.2 1234 80\r\nping"))
+
+	if n, err := conn.Read(make([]byte, 4)); n > 0 {
+		t.Fatal("connection served")
+	} else if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Fatal("connection left hanging")
+	}
+
+	if err = <-reported; !errors.Is(err, unix.EMFILE) {
+		t.Fatalf("reported %v", err)
+	}
+
+	restore()
+
+	c := s.Dial(t)
+	c.SendString("PROXY UNKNOWN\r\nping").ExpectString("ping").Close()
+}
@@ -0,0 +1,266 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package test
+
+import (
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+	"github.com/fengyfei/nuts/linux/tcp/tcptest"
+	"github.com/fengyfei/nuts/scheduler"
+)
+
+// replies passes on what the connections of a client read.
+type replies struct {
+	tcptest.Echo
+	c chan string
+}
+
+func (r replies) OnReadMessage(conn net.Conn) error {
+	b := make([]byte, 64)
+
+	n, err := conn.Read(b)
+	if n > 0 {
+		r.c <- string(b[:n])
+	}
+
+	return err
+}
+
+func (r replies) expect(t *testing.T, want string) {
+	t.Helper()
+
+	select {
+	case got := <-r.c:
+		if got != want {
+			t.Fatalf("read %q, want %q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%q never read", want)
+	}
+}
+
+func newClient(t *testing.T, c *tcp.ClientConfig) (*tcp.Client, replies) {
+	r := replies{c: make(chan string, 16)}
+
+	cl, err := tcp.NewClient(c, r, scheduler.New(64, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cl, r
+}
+
+func send(t *testing.T, conn net.Conn, s string) {
+	t.Helper()
+
+	if _, err := conn.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func descriptors() int {
+	entries, _ := os.ReadDir("/proc/self/fd")
+	return len(entries)
+}
+
+func TestClientPool(t *testing.T) {
+	s := tcptest.NewServer(nil)
+	defer s.Close()
+
+	cl, r := newClient(t, &tcp.ClientConfig{PoolSize: 3})
+	defer cl.Close()
+
+	// The pool fills in the background, then hands its connections out in
+	// turn.
+	seen := make(map[*tcp.Conn]bool)
+	deadline := time.Now().Add(5 * time.Second)
+
+	for len(seen) < 3 && time.Now().Before(deadline) {
+		conn, err := cl.Get(s.Addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		seen[conn] = true
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if len(seen) != 3 {
+		t.Fatalf("%d connections handed out", len(seen))
+	}
+
+	for conn := range seen {
+		send(t, conn, "ping")
+		r.expect(t, "ping")
+	}
+}
+
+func TestClientReconnect(t *testing.T) {
+	s := tcptest.NewServer(failing{})
+	defer s.Close()
+
+	cl, r := newClient(t, &tcp.ClientConfig{MinBackoff: time.Minute})
+	defer cl.Close()
+
+	first, err := cl.Get(s.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A connection the server closes is replaced.
+	send(t, first, "bye")
+
+	var second *tcp.Conn
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if second, err = cl.Get(s.Addr); err == nil && second != first {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("never reconnected, %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	send(t, second, "ping")
+	r.expect(t, "ping")
+
+	// Once the server is gone, Get fails fast with the last dial error
+	// until the backoff expires.
+	s.Close()
+	send(t, second, "bye")
+
+	var dialErr error
+	for deadline := time.Now().Add(5 * time.Second); dialErr == nil; {
+		if _, dialErr = cl.Get(s.Addr); dialErr == nil && time.Now().After(deadline) {
+			t.Fatal("Get never failed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	start := time.Now()
+	if _, err = cl.Get(s.Addr); err != dialErr {
+		t.Fatalf("Get failed with %v, then %v", dialErr, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("Get took %v during the backoff", elapsed)
+	}
+}
+
+func TestClientClose(t *testing.T) {
+	s := tcptest.NewServer(nil)
+	defer s.Close()
+
+	fds := descriptors()
+
+	cl, r := newClient(t, &tcp.ClientConfig{})
+
+	pooled, err := cl.Get(s.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dialed, err := cl.Dial(s.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = cl.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = cl.Get(s.Addr); !errors.Is(err, tcp.ErrClientClosed) {
+		t.Fatalf("Get after Close: %v", err)
+	}
+
+	if _, err = cl.Dial(s.Addr); !errors.Is(err, tcp.ErrClientClosed) {
+		t.Fatalf("Dial after Close: %v", err)
+	}
+
+	// Pooled connections are closed, dialed ones are still served.
+	if _, err = pooled.Write([]byte("ping")); err == nil {
+		t.Fatal("pooled connection left open")
+	}
+
+	send(t, dialed, "ping")
+	r.expect(t, "ping")
+
+	// The poller stops along with the last connection.
+	dialed.Close()
+
+	for deadline := time.Now().Add(time.Second); descriptors() > fds; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d descriptors open, %d before the client", descriptors(), fds)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// dropping closes every connection as soon as it's open.
+type dropping struct {
+	tcptest.Echo
+}
+
+func (dropping) OnOpen(conn net.Conn) {
+	conn.Close()
+}
+
+func TestClientOpenClose(t *testing.T) {
+	s := tcptest.NewServer(nil)
+	defer s.Close()
+
+	cl, err := tcp.NewClient(&tcp.ClientConfig{}, dropping{}, scheduler.New(64, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	// Closing takes the pool's lock, Get doesn't hold it while dialing.
+	done := make(chan error, 1)
+	go func() {
+		_, err := cl.Get(s.Addr)
+		done <- err
+	}()
+
+	select {
+	case err = <-done:
+		if !errors.Is(err, net.ErrClosed) {
+			t.Fatalf("Get returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Get never returned")
+	}
+}
@@ -0,0 +1,165 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package test
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+	"github.com/fengyfei/nuts/linux/tcp/tcptest"
+)
+
+// failing echoes a message, then fails on "fail" or closes on "bye".
+type failing struct {
+	tcptest.Echo
+}
+
+func (h failing) OnReadMessage(conn net.Conn) error {
+	b := make([]byte, 64)
+
+	n, err := conn.Read(b)
+	switch string(b[:n]) {
+	case "fail":
+		return errors.New("failed")
+	case "bye":
+		return conn.Close()
+	}
+
+	conn.Write(b[:n])
+	return err
+}
+
+// releasing lets the server read into pooled buffers.
+type releasing struct {
+	tcptest.Echo
+}
+
+func (releasing) OnReadBuffer(conn net.Conn, b *tcp.Buffer) error {
+	_, err := conn.Write(b.B)
+	b.Release()
+
+	return err
+}
+
+func TestClose(t *testing.T) {
+	s := tcptest.NewServer(nil)
+	defer s.Close()
+
+	c := s.Dial(t)
+	c.SendString("hello").ExpectString("hello").Close()
+	c.WaitFor(tcptest.Close).ExpectEvents(tcptest.Read, tcptest.Close)
+
+	if err := s.CheckLeaks(time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHalfClose(t *testing.T) {
+	for _, h := range []tcp.Handler{tcptest.Echo{}, releasing{}} {
+		s := tcptest.NewServer(h)
+
+		// Data sent along with the FIN is handled before closing.
+		c := s.Dial(t)
+		c.SendString("request").CloseWrite().ExpectString("request").ExpectEOF()
+		c.WaitFor(tcptest.Close).ExpectEvents(tcptest.Read, tcptest.Close)
+		c.Close()
+
+		if err := s.CheckLeaks(time.Second); err != nil {
+			t.Fatal(err)
+		}
+		s.Close()
+	}
+}
+
+func TestReset(t *testing.T) {
+	s := tcptest.NewServer(nil)
+	defer s.Close()
+
+	c := s.Dial(t)
+	c.SendString("ping").ExpectString("ping").Reset()
+	c.WaitFor(tcptest.Close).ExpectEvents(tcptest.Read, tcptest.Close)
+
+	idle := s.Dial(t)
+	idle.Reset()
+	idle.WaitFor(tcptest.Close).ExpectEvents(tcptest.Close)
+
+	if err := s.CheckLeaks(time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandlerError(t *testing.T) {
+	s := tcptest.NewServer(failing{})
+	defer s.Close()
+
+	c := s.Dial(t)
+	c.SendString("one").ExpectString("one")
+	c.SendString("fail").ExpectEOF()
+	c.WaitFor(tcptest.Close).ExpectEvents(tcptest.Read, tcptest.Error, tcptest.Close)
+	c.Close()
+
+	events := s.Recorder.Events(c.Addr())
+	if err := events[len(events)-3].Err; err == nil || err.Error() != "failed" {
+		t.Fatalf("read returned %v", err)
+	}
+
+	// A connection closed by the handler gets no more callbacks.
+	bye := s.Dial(t)
+	bye.SendString("bye").ExpectEOF()
+	bye.WaitFor(tcptest.Close).ExpectEvents(tcptest.Read, tcptest.Close)
+	bye.Close()
+
+	if err := s.CheckLeaks(time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNoLeaks(t *testing.T) {
+	s := tcptest.NewServer(releasing{})
+	defer s.Close()
+
+	for i := 0; i < 200; i++ {
+		c := s.Dial(t)
+		c.SendString("x").ExpectString("x")
+
+		if i%2 == 0 {
+			c.Close()
+		} else {
+			c.Reset()
+		}
+	}
+
+	if err := s.CheckLeaks(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
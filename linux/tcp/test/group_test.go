@@ -0,0 +1,209 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package test
+
+import (
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+	"github.com/fengyfei/nuts/linux/tcp/tcptest"
+	"github.com/fengyfei/nuts/scheduler"
+)
+
+// subscriber joins the topic sent as "+topic", leaves it on "-topic" and
+// publishes "tick" to it on "!topic", then answers "ok".
+type subscriber struct {
+	tcptest.Echo
+	server atomic.Pointer[tcp.Server]
+}
+
+func (h *subscriber) OnReadMessage(conn net.Conn) error {
+	b := make([]byte, 64)
+
+	n, err := conn.Read(b)
+	if err != nil {
+		return err
+	}
+
+	c, topic := conn.(*tcp.Conn), string(b[1:n])
+
+	switch b[0] {
+	case '+':
+		c.Join(topic)
+	case '-':
+		c.Leave(topic)
+	case '!':
+		h.server.Load().Publish(topic, []byte("tick"))
+	}
+
+	_, err = conn.Write([]byte("ok"))
+	return err
+}
+
+// members waits for topic to have n members.
+func members(t *testing.T, s *tcp.Server, topic string, n int) {
+	deadline := time.Now().Add(5 * time.Second)
+
+	for s.Members(topic) != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s has %d members, want %d", topic, s.Members(topic), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	h := &subscriber{}
+	s := tcptest.NewServer(h)
+	defer s.Close()
+	h.server.Store(s.Server)
+
+	first, second, other := s.Dial(t), s.Dial(t), s.Dial(t)
+	first.SendString("+news").ExpectString("ok")
+	second.SendString("+news").ExpectString("ok")
+	other.SendString("+sports").ExpectString("ok")
+
+	if n := s.Publish("news", []byte("hello")); n != 2 {
+		t.Fatalf("published to %d connections", n)
+	}
+
+	first.ExpectString("hello")
+	second.ExpectString("hello")
+
+	// Only members get the message.
+	other.SendString("!sports").ExpectString("ok")
+	other.ExpectString("tick")
+
+	second.SendString("-news").ExpectString("ok")
+	members(t, s.Server, "news", 1)
+
+	// Closed connections leave their topics.
+	first.Close()
+	members(t, s.Server, "news", 0)
+
+	second.Close()
+	other.Close()
+
+	if err := s.CheckLeaks(time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPublishSlowSubscriber(t *testing.T) {
+	s := tcptest.NewServerConfig(&tcp.Config{
+		Socket:         tcp.SocketOptions{WriteBuffer: 8 << 10},
+		PublishTimeout: 100 * time.Millisecond,
+	}, &subscriber{})
+	defer s.Close()
+
+	slow, fast := s.Dial(t), s.Dial(t)
+	slow.Conn().SetReadBuffer(4 << 10)
+	slow.SendString("+feed").ExpectString("ok")
+	fast.SendString("+feed").ExpectString("ok")
+
+	const total = 200
+	msg := make([]byte, 32<<10)
+
+	received := make(chan int64, 1)
+	go func() {
+		fast.Conn().SetReadDeadline(time.Now().Add(10 * time.Second))
+		n, _ := io.CopyN(io.Discard, fast.Conn(), total*int64(len(msg)))
+		received <- n
+	}()
+
+	// The slow subscriber never reads, it's dropped once its buffers are
+	// full, without holding back the other.
+	for i := 0; i < total; i++ {
+		s.Publish("feed", msg)
+	}
+
+	if n := <-received; n != total*int64(len(msg)) {
+		t.Fatalf("fast subscriber got %d bytes", n)
+	}
+
+	members(t, s.Server, "feed", 1)
+
+	slow.Close()
+	fast.Close()
+}
+
+func TestPublishFromHandler(t *testing.T) {
+	h := &subscriber{}
+
+	// A single worker and a queue of one, the fan-out can't wait on it.
+	s, err := tcp.StartServer(&tcp.Config{Address: "127.0.0.1:0"}, h, scheduler.New(1, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	h.server.Store(s)
+
+	command := func(conn net.Conn, cmd string) {
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		conn.Write([]byte(cmd))
+
+		b := make([]byte, 2)
+		if _, err := io.ReadFull(conn, b); err != nil || string(b) != "ok" {
+			t.Fatalf("%s: read %q: %v", cmd, b, err)
+		}
+	}
+
+	// Enough members for several batches.
+	conns := make([]net.Conn, 200)
+	for i := range conns {
+		conn, err := net.Dial("tcp", s.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		command(conn, "+all")
+		conns[i] = conn
+	}
+
+	publisher, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer publisher.Close()
+
+	command(publisher, "!all")
+
+	for i, conn := range conns {
+		b := make([]byte, 4)
+		if _, err := io.ReadFull(conn, b); err != nil || string(b) != "tick" {
+			t.Fatalf("member %d read %q: %v", i, b, err)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+	"github.com/fengyfei/nuts/linux/tcp/httpd"
+	"github.com/fengyfei/nuts/scheduler"
+)
+
+func startHTTP(t *testing.T) *tcp.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.Copy(w, r.Body)
+	})
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	s, err := tcp.StartServer(&tcp.Config{Address: "127.0.0.1:0"}, httpd.NewServer(&httpd.Config{MaxBodySize: 1024}, mux), scheduler.New(8, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestHTTPKeepAlive(t *testing.T) {
+	s := startHTTP(t)
+	defer s.Close()
+
+	url := "http://" + s.Addr().String()
+	client := &http.Client{Transport: &http.Transport{MaxIdleConnsPerHost: 1}}
+
+	for i := 0; i < 5; i++ {
+		reused := false
+		trace := &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) { reused = info.Reused },
+		}
+
+		req, _ := http.NewRequest(http.MethodPost, url+"/echo", strings.NewReader(fmt.Sprint("body ", i)))
+		resp, err := client.Do(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK || string(b) != fmt.Sprint("body ", i) {
+			t.Fatalf("%d %q", resp.StatusCode, b)
+		}
+
+		if i > 0 && !reused {
+			t.Fatalf("request %d didn't reuse the connection", i)
+		}
+	}
+
+	resp, err := client.Post(url+"/echo", "text/plain", strings.NewReader(strings.Repeat("x", 2048)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized body got %d", resp.StatusCode)
+	}
+}
+
+func TestHTTPPipelining(t *testing.T) {
+	s := startHTTP(t)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Three requests in one write, the second one chunked.
+	conn.Write([]byte("GET /health HTTP/1.1\r\nHost: x\r\n\r\n" +
+		"POST /echo HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n6\r\n world\r\n0\r\n\r\n" +
+		"GET /missing HTTP/1.1\r\nHost: x\r\n\r\n"))
+
+	r := bufio.NewReader(conn)
+	for _, want := range []struct {
+		status int
+		body   string
+	}{
+		{http.StatusOK, "ok"},
+		{http.StatusOK, "hello world"},
+		{http.StatusNotFound, "404 page not found\n"},
+	} {
+		resp, err := http.ReadResponse(r, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != want.status || string(b) != want.body {
+			t.Fatalf("%d %q", resp.StatusCode, b)
+		}
+	}
+
+	// A panicking handler answers 500 and closes the connection.
+	conn.Write([]byte("GET /panic HTTP/1.1\r\nHost: x\r\n\r\n"))
+
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusInternalServerError || !resp.Close {
+		t.Fatalf("%d close=%v", resp.StatusCode, resp.Close)
+	}
+
+	if _, err = r.ReadByte(); err != io.EOF {
+		t.Fatalf("connection not closed: %v", err)
+	}
+}
+
+func TestHTTPConnectionClose(t *testing.T) {
+	var readErrors int32
+
+	s, err := tcp.StartServer(&tcp.Config{
+		Address: "127.0.0.1:0",
+		OnReadError: func(*tcp.Conn, error) {
+			atomic.AddInt32(&readErrors, 1)
+		},
+	}, httpd.NewServer(&httpd.Config{}, http.NotFoundHandler()), scheduler.New(8, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	fmt.Fprint(conn, "GET / HTTP/1.1\r\nHost: test\r\nConnection: close\r\n\r\n")
+
+	resp, err := io.ReadAll(conn)
+	if err != nil || !strings.HasPrefix(string(resp), "HTTP/1.1 404") {
+		t.Fatalf("read %q, %v", resp, err)
+	}
+
+	// Closing as asked is no read error.
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&readErrors); n != 0 {
+		t.Fatalf("%d read errors", n)
+	}
+}
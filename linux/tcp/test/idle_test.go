@@ -0,0 +1,235 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package test
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+	"github.com/fengyfei/nuts/linux/tcp/tcptest"
+	"github.com/fengyfei/nuts/scheduler"
+)
+
+// heartbeat sends "ping" on WriteIdle and closes on ReadIdle.
+type heartbeat struct {
+	tcptest.Echo
+}
+
+func (heartbeat) OnIdle(conn net.Conn, state tcp.IdleState) {
+	switch state {
+	case tcp.WriteIdle:
+		conn.Write([]byte("ping"))
+	case tcp.ReadIdle:
+		conn.Close()
+	}
+}
+
+func TestReadIdle(t *testing.T) {
+	s := tcptest.NewServerConfig(&tcp.Config{
+		ReadIdleTimeout: 100 * time.Millisecond,
+	}, nil)
+	defer s.Close()
+
+	// Activity keeps the connection open.
+	c := s.Dial(t)
+	for i := 0; i < 5; i++ {
+		c.SendString("hi").ExpectString("hi")
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	start := time.Now()
+	c.ExpectEOF()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("closed after %v", elapsed)
+	}
+
+	c.WaitFor(tcptest.Close).ExpectEvents(tcptest.Read, tcptest.Close)
+	c.Close()
+
+	if err := s.CheckLeaks(time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	s := tcptest.NewServerConfig(&tcp.Config{
+		ReadIdleTimeout:  time.Second,
+		WriteIdleTimeout: 100 * time.Millisecond,
+	}, heartbeat{})
+	defer s.Close()
+
+	c := s.Dial(t)
+	c.ExpectString("ping").ExpectString("ping")
+
+	events := s.Recorder.Events(c.Addr())
+	for _, e := range events {
+		if e.Kind != tcptest.Idle || e.State != tcp.WriteIdle {
+			t.Fatalf("unexpected event %+v", e)
+		}
+	}
+
+	// The handler closes the connection once it's been silent too long.
+	c.WaitFor(tcptest.Close)
+	c.Close()
+
+	if err := s.CheckLeaks(time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMaxLifetime(t *testing.T) {
+	s := tcptest.NewServerConfig(&tcp.Config{
+		MaxLifetime: 200 * time.Millisecond,
+	}, heartbeat{})
+	defer s.Close()
+
+	// Closed even while active.
+	c := s.Dial(t)
+	deadline := time.Now().Add(5 * time.Second)
+	for !s.Recorder.Wait(c.Addr(), tcptest.Close, 20*time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("connection outlived MaxLifetime")
+		}
+		c.SendString("hi").ExpectString("hi")
+	}
+
+	expired := false
+	for _, e := range s.Recorder.Events(c.Addr()) {
+		expired = expired || e.Kind == tcptest.Idle && e.State == tcp.LifetimeExpired
+	}
+
+	if !expired {
+		t.Fatal("closed without LifetimeExpired")
+	}
+	c.Close()
+
+	if err := s.CheckLeaks(time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIdleManyConnections(t *testing.T) {
+	s := tcptest.NewServerConfig(&tcp.Config{
+		ReadIdleTimeout: 100 * time.Millisecond,
+	}, nil)
+	defer s.Close()
+
+	clients := make([]*tcptest.Client, 100)
+	for i := range clients {
+		clients[i] = s.Dial(t)
+	}
+
+	for _, c := range clients {
+		c.ExpectEOF()
+		c.Close()
+	}
+
+	if err := s.CheckLeaks(time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestShutdownIdle(t *testing.T) {
+	s := tcptest.NewServerConfig(&tcp.Config{
+		ReadIdleTimeout: 100 * time.Millisecond,
+	}, heartbeat{})
+	defer s.Close()
+
+	// A silent peer is closed while draining, Shutdown doesn't wait for ctx.
+	c := s.Dial(t)
+	defer c.Close()
+
+	c.SendString("hi").ExpectString("hi")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("drained after %v", elapsed)
+	}
+}
+
+// blocking holds a worker on every message until release is closed.
+type blocking struct {
+	tcptest.Echo
+	release chan struct{}
+}
+
+func (h blocking) OnReadMessage(conn net.Conn) error {
+	conn.Read(make([]byte, 64))
+	<-h.release
+	return nil
+}
+
+func TestIdleBusyPool(t *testing.T) {
+	h := blocking{release: make(chan struct{})}
+	defer close(h.release)
+
+	s, err := tcp.StartServer(&tcp.Config{
+		Address:         "127.0.0.1:0",
+		ReadIdleTimeout: 100 * time.Millisecond,
+	}, h, scheduler.New(1, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// The worker runs one, the pool holds another and its queue the last.
+	for i := 0; i < 3; i++ {
+		conn, err := net.Dial("tcp", s.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		conn.Write([]byte("block"))
+	}
+
+	// Idle connections are still closed with the pool full.
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err = conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("read %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+	"github.com/fengyfei/nuts/linux/tcp/tcptest"
+	"github.com/fengyfei/nuts/scheduler"
+)
+
+// childEnv holds the address the child process of TestPassListeners
+// serves.
+const childEnv = "NUTS_TEST_CHILD"
+
+// TestPassListenersChild is the child process of TestPassListeners, it
+// serves until its stdin is closed.
+func TestPassListenersChild(t *testing.T) {
+	addr := os.Getenv(childEnv)
+	if addr == "" {
+		t.Skip("run by TestPassListeners")
+	}
+
+	// Binding again would fail, the parent still listens.
+	s, err := tcp.StartServer(&tcp.Config{
+		Listeners: []tcp.Listener{{Name: "child", Address: addr}},
+	}, naming{}, scheduler.New(64, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	fmt.Println("ready")
+	io.Copy(io.Discard, os.Stdin)
+}
+
+// hold dials address and checks which server answers, the connection is
+// left open.
+func hold(t *testing.T, address, want string) (net.Conn, *bufio.Reader) {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := bufio.NewReader(conn)
+	conn.Write([]byte("?"))
+
+	if line, err := r.ReadString('\n'); err != nil || line != want+"\n" {
+		t.Fatalf("read %q, %v", line, err)
+	}
+
+	return conn, r
+}
+
+func TestPassListeners(t *testing.T) {
+	s := tcptest.NewServerConfig(&tcp.Config{
+		Listeners: []tcp.Listener{{Name: "parent", Address: "127.0.0.1:0"}},
+	}, naming{})
+	defer s.Close()
+
+	held, r := hold(t, s.Addr, "parent")
+	defer held.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestPassListenersChild$")
+	cmd.Env = append(os.Environ(), childEnv+"="+s.Addr)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.PassListeners(cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Wait()
+	defer stdin.Close()
+
+	if line, err := bufio.NewReader(stdout).ReadString('\n'); err != nil || line != "ready\n" {
+		t.Fatalf("child said %q, %v", line, err)
+	}
+
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		shutdown <- s.Shutdown(ctx)
+	}()
+
+	// Once the parent stops accepting, the child gets every connection.
+	for deadline := time.Now().Add(5 * time.Second); ask(t, "tcp", s.Addr) != "child"; {
+		if time.Now().After(deadline) {
+			t.Fatal("the child never answered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for i := 0; i < 5; i++ {
+		if got := ask(t, "tcp", s.Addr); got != "child" {
+			t.Fatalf("%s answered after the handoff", got)
+		}
+	}
+
+	// The parent still serves what it accepted, until it's closed.
+	held.Write([]byte("?"))
+	if line, err := r.ReadString('\n'); err != nil || line != "parent\n" {
+		t.Fatalf("read %q, %v", line, err)
+	}
+
+	select {
+	case err = <-shutdown:
+		t.Fatalf("Shutdown returned %v with a connection open", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	held.Close()
+
+	select {
+	case err = <-shutdown:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown never returned")
+	}
+
+	stdin.Close()
+	if err = cmd.Wait(); err != nil {
+		t.Fatalf("child: %v", err)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	s := tcptest.NewServer(nil)
+	defer s.Close()
+
+	c := s.Dial(t)
+	c.SendString("ping").ExpectString("ping")
+
+	// Shutdown gives up when ctx is done first.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := s.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Shutdown returned %v", err)
+	}
+
+	// New connections are refused, established ones still served.
+	if conn, err := net.Dial("tcp", s.Addr); err == nil {
+		conn.Close()
+		t.Fatal("dialed after Shutdown")
+	}
+
+	c.SendString("ping").ExpectString("ping")
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		c.Close()
+	}()
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.CheckLeaks(time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
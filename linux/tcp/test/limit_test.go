@@ -0,0 +1,156 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+	"github.com/fengyfei/nuts/linux/tcp/tcptest"
+)
+
+func limited(s *tcptest.Server) uint64 {
+	var n uint64
+
+	for _, st := range s.Stats() {
+		n += st.Limited
+	}
+
+	return n
+}
+
+func TestThrottle(t *testing.T) {
+	s := tcptest.NewServerConfig(&tcp.Config{
+		RateLimit: &tcp.RateLimit{MessagesPerSecond: 20},
+	}, nil)
+	defer s.Close()
+
+	c := s.Dial(t)
+	defer c.Close()
+
+	// The first second worth goes through at once, the rest at the rate.
+	start := time.Now()
+	for i := 0; i < 30; i++ {
+		c.SendString("ping").ExpectString("ping")
+	}
+
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Fatalf("30 messages in %v", elapsed)
+	}
+
+	if limited(s) == 0 {
+		t.Fatal("the connection was never limited")
+	}
+}
+
+func TestThrottleGlobal(t *testing.T) {
+	var handled int64
+
+	s := tcptest.NewServerConfig(&tcp.Config{
+		RateLimit: &tcp.RateLimit{GlobalMessagesPerSecond: 10},
+	}, nil)
+	defer s.Close()
+
+	// Concurrent connections don't overdraw the shared budget.
+	var wg sync.WaitGroup
+	start := time.Now()
+	window := 200 * time.Millisecond
+
+	for i := 0; i < 10; i++ {
+		c := s.Dial(t)
+		defer c.Close()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 2; j++ {
+				c.SendString("ping").ExpectString("ping")
+				if time.Since(start) < window {
+					atomic.AddInt64(&handled, 1)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// The budget plus what refills during the window.
+	if n := atomic.LoadInt64(&handled); n > 10+3 {
+		t.Fatalf("%d messages handled within %v", n, window)
+	}
+}
+
+func TestDrop(t *testing.T) {
+	s := tcptest.NewServerConfig(&tcp.Config{
+		RateLimit: &tcp.RateLimit{MessagesPerSecond: 5, Action: tcp.Drop},
+	}, nil)
+	defer s.Close()
+
+	c := s.Dial(t)
+	defer c.Close()
+
+	for i := 0; i < 5; i++ {
+		c.SendString("ping").ExpectString("ping")
+	}
+
+	// Over budget, dropped, then the budget refills.
+	c.SendString("lost")
+	time.Sleep(400 * time.Millisecond)
+	c.SendString("kept").ExpectString("kept")
+
+	if limited(s) == 0 {
+		t.Fatal("the connection was never limited")
+	}
+}
+
+func TestDisconnect(t *testing.T) {
+	s := tcptest.NewServerConfig(&tcp.Config{
+		RateLimit: &tcp.RateLimit{MessagesPerSecond: 5, Action: tcp.Disconnect},
+	}, nil)
+	defer s.Close()
+
+	c := s.Dial(t)
+
+	for i := 0; i < 5; i++ {
+		c.SendString("ping").ExpectString("ping")
+	}
+
+	// Unread data makes the close a reset.
+	c.SendString("over").WaitFor(tcptest.Close)
+	c.Close()
+
+	if err := s.CheckLeaks(time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package test
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+	"github.com/fengyfei/nuts/linux/tcp/tcptest"
+)
+
+// naming answers each line with the name of the listener that accepted
+// the connection.
+type naming struct {
+	tcptest.Echo
+}
+
+func (naming) OnReadMessage(conn net.Conn) error {
+	b := make([]byte, 64)
+
+	if _, err := conn.Read(b); err != nil {
+		return err
+	}
+
+	_, err := conn.Write([]byte(conn.(*tcp.Conn).Listener().Name + "\n"))
+	return err
+}
+
+func ask(t *testing.T, network, address string) string {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.Write([]byte("?")); err != nil {
+		t.Fatal(err)
+	}
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return line[:len(line)-1]
+}
+
+func TestListeners(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "private.sock")
+
+	s := tcptest.NewServerConfig(&tcp.Config{
+		Listeners: []tcp.Listener{
+			{Name: "public", Address: "127.0.0.1:0"},
+			{Name: "private", Address: "127.0.0.1:0"},
+			{Name: "local", Network: "unix", Address: sock},
+		},
+	}, naming{})
+	defer s.Close()
+
+	listeners := s.Listeners()
+	if len(listeners) != 3 {
+		t.Fatalf("got %d listeners", len(listeners))
+	}
+
+	for _, l := range listeners {
+		if got := ask(t, l.Network, l.Address); got != l.Name {
+			t.Fatalf("%s answered %q", l.Name, got)
+		}
+	}
+
+	if err := s.Listen(tcp.Listener{Name: "public", Address: "127.0.0.1:0"}); err != tcp.ErrListenerExists {
+		t.Fatalf("duplicate listener: %v", err)
+	}
+
+	if err := s.Listen(tcp.Listener{Name: "admin", Address: "127.0.0.1:0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	admin := s.Listeners()[3]
+	if got := ask(t, "tcp", admin.Address); got != "admin" {
+		t.Fatalf("admin answered %q", got)
+	}
+
+	// Connections outlive their listener, once accepted.
+	conn, err := net.Dial("tcp", listeners[1].Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+
+	conn.Write([]byte("?"))
+	if line, err := r.ReadString('\n'); err != nil || line != "private\n" {
+		t.Fatalf("read %q, %v", line, err)
+	}
+
+	if err = s.Unlisten("private"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.Unlisten("private"); err != tcp.ErrNoListener {
+		t.Fatalf("unknown listener: %v", err)
+	}
+
+	if _, err = net.Dial("tcp", listeners[1].Address); err == nil {
+		t.Fatal("private listener still accepts")
+	}
+
+	conn.Write([]byte("?"))
+	if line, err := r.ReadString('\n'); err != nil || line != "private\n" {
+		t.Fatalf("read %q, %v", line, err)
+	}
+}
@@ -0,0 +1,153 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package test
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+	"github.com/fengyfei/nuts/linux/tcp/tcptest"
+)
+
+var errRefused = errors.New("refused")
+
+// refusing turns every connection away.
+type refusing struct {
+	tcptest.Echo
+}
+
+func (refusing) OnAccept() error {
+	return errRefused
+}
+
+// journal records the events of a server, in order.
+type journal struct {
+	mu     sync.Mutex
+	events []string
+	errs   []error
+}
+
+func (j *journal) add(event string, err error) {
+	j.mu.Lock()
+	j.events = append(j.events, event)
+	j.errs = append(j.errs, err)
+	j.mu.Unlock()
+}
+
+func (j *journal) wait(t *testing.T, n int) ([]string, []error) {
+	deadline := time.Now().Add(time.Second)
+
+	for {
+		j.mu.Lock()
+		events, errs := j.events, j.errs
+		j.mu.Unlock()
+
+		if len(events) >= n {
+			return events, errs
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("got events %v, want %d", events, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (j *journal) config() *tcp.Config {
+	return &tcp.Config{
+		Logger: tcp.LoggerFunc(func(msg string, keyvals ...interface{}) {
+			if len(keyvals)%2 != 0 {
+				panic("odd keyvals for " + msg)
+			}
+		}),
+		OnListen:     func(net.Addr) { j.add("listen", nil) },
+		OnConnAccept: func(*tcp.Conn) { j.add("accept", nil) },
+		OnConnReject: func(_ net.Addr, err error) { j.add("reject", err) },
+		OnReadError:  func(_ *tcp.Conn, err error) { j.add("read", err) },
+		OnShutdown:   func() { j.add("shutdown", nil) },
+	}
+}
+
+func TestLifecycleHooks(t *testing.T) {
+	var j journal
+
+	s := tcptest.NewServerConfig(j.config(), failing{})
+
+	c := s.Dial(t)
+	c.SendString("fail").ExpectEOF()
+	c.Close()
+
+	s.Close()
+	s.Close()
+
+	events, errs := j.wait(t, 4)
+	want := []string{"listen", "accept", "read", "shutdown"}
+	if len(events) != len(want) {
+		t.Fatalf("got events %v, want %v", events, want)
+	}
+
+	for i := range want {
+		if events[i] != want[i] {
+			t.Fatalf("got events %v, want %v", events, want)
+		}
+	}
+
+	if errs[2] == nil || errs[2].Error() != "failed" {
+		t.Fatalf("read error is %v", errs[2])
+	}
+}
+
+func TestRejectHook(t *testing.T) {
+	var j journal
+
+	s := tcptest.NewServerConfig(j.config(), refusing{})
+	defer s.Close()
+
+	c := s.Dial(t)
+	c.ExpectEOF()
+	c.Close()
+
+	events, errs := j.wait(t, 2)
+	if events[1] != "reject" || errs[1] != errRefused {
+		t.Fatalf("got events %v, errors %v", events, errs)
+	}
+
+	if len(s.Recorder.Events(c.Addr())) != 0 {
+		t.Fatal("a rejected connection reached the handler")
+	}
+
+	if err := s.CheckLeaks(time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
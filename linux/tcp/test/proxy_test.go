@@ -0,0 +1,98 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+	"github.com/fengyfei/nuts/linux/tcp/tcptest"
+)
+
+// addressing answers each message with the address of the client.
+type addressing struct {
+	tcptest.Echo
+}
+
+func (addressing) OnReadMessage(conn net.Conn) error {
+	b := make([]byte, 64)
+
+	if _, err := conn.Read(b); err != nil {
+		return err
+	}
+
+	_, err := conn.Write([]byte(conn.RemoteAddr().String() + "\n"))
+	return err
+}
+
+func TestProxyProtocol(t *testing.T) {
+	rejected := make(chan error, 1)
+
+	s := tcptest.NewServerConfig(&tcp.Config{
+		Proxy: &tcp.ProxyConfig{Trusted: []string{"127.0.0.1"}},
+		OnConnReject: func(_ net.Addr, err error) {
+			rejected <- err
+		},
+	}, addressing{})
+	defer s.Close()
+
+	c := s.Dial(t)
+	c.SendString("PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n?").ExpectString("192.0.2.1:56324\n")
+	c.Close()
+
+	bad := s.Dial(t)
+	bad.SendString("PROXY TCP4 192.0.2.1\r\n").ExpectEOF()
+	bad.Close()
+
+	select {
+	case err := <-rejected:
+		if err != tcp.ErrProxyHeader {
+			t.Fatalf("rejected with %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("invalid header accepted")
+	}
+}
+
+func TestProxyUntrusted(t *testing.T) {
+	s := tcptest.NewServerConfig(&tcp.Config{
+		Proxy: &tcp.ProxyConfig{Trusted: []string{"10.0.0.0/8"}},
+	}, nil)
+	defer s.Close()
+
+	// A header from anyone else is data, the address can't be spoofed.
+	header := "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n"
+
+	c := s.Dial(t)
+	c.SendString(header).ExpectString(header)
+	c.Close()
+}
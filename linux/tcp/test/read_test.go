@@ -0,0 +1,142 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package test
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+	"github.com/fengyfei/nuts/scheduler"
+)
+
+const (
+	chunkSize = 4096
+)
+
+// counter counts the bytes it reads and signals once it has seen want.
+type counter struct {
+	want int64
+	got  int64
+	done chan struct{}
+}
+
+func (h *counter) OnAccept() error  { return nil }
+func (h *counter) OnClose(net.Conn) {}
+func (h *counter) OnError(net.Conn) {}
+
+func (h *counter) add(n int) {
+	if atomic.AddInt64(&h.got, int64(n)) == atomic.LoadInt64(&h.want) {
+		h.done <- struct{}{}
+	}
+}
+
+// allocating reads the way handlers had to before buffers were pooled.
+type allocating struct {
+	counter
+}
+
+func (h *allocating) OnReadMessage(conn net.Conn) error {
+	b := make([]byte, chunkSize)
+
+	n, err := conn.Read(b)
+	h.add(n)
+
+	return err
+}
+
+// borrowing lets the server read into pooled buffers.
+type borrowing struct {
+	counter
+}
+
+func (h *borrowing) OnReadMessage(conn net.Conn) error {
+	return nil
+}
+
+func (h *borrowing) OnReadBuffer(conn net.Conn, b *tcp.Buffer) error {
+	h.add(len(b.B))
+	b.Release()
+
+	return nil
+}
+
+// benchmarkRead streams b.N chunks to a connection dialed by tcp.Client,
+// which serves reads exactly like tcp.Server does.
+func benchmarkRead(b *testing.B, h tcp.Handler, c *counter) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer ln.Close()
+
+	client, err := tcp.NewClient(&tcp.ClientConfig{}, h, scheduler.New(64, 0))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	conn, err := client.Dial(ln.Addr().String())
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer conn.Close()
+
+	peer, err := ln.Accept()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer peer.Close()
+
+	chunk := make([]byte, chunkSize)
+	atomic.StoreInt64(&c.want, int64(b.N)*chunkSize)
+
+	b.SetBytes(chunkSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := peer.Write(chunk); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	<-c.done
+}
+
+func BenchmarkReadMessage(b *testing.B) {
+	h := &allocating{counter{done: make(chan struct{}, 1)}}
+	benchmarkRead(b, h, &h.counter)
+}
+
+func BenchmarkReadBuffer(b *testing.B) {
+	h := &borrowing{counter{done: make(chan struct{}, 1)}}
+	benchmarkRead(b, h, &h.counter)
+}
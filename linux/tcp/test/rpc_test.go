@@ -0,0 +1,320 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+	"github.com/fengyfei/nuts/linux/tcp/rpc"
+	"github.com/fengyfei/nuts/scheduler"
+	"golang.org/x/sys/unix"
+)
+
+func startRPC(t *testing.T, calls *scheduler.Pool) (*tcp.Server, *rpc.Server, *rpc.Client) {
+	srv := rpc.NewServer(calls)
+	srv.Register("echo", func(conn *tcp.Conn, payload []byte) ([]byte, error) {
+		return payload, nil
+	})
+	srv.Register("fail", func(conn *tcp.Conn, payload []byte) ([]byte, error) {
+		return nil, errors.New("failed")
+	})
+
+	s, err := tcp.StartServer(&tcp.Config{Address: "127.0.0.1:0"}, srv, scheduler.New(64, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := rpc.NewClient(&tcp.ClientConfig{}, scheduler.New(64, 4))
+	if err != nil {
+		s.Close()
+		t.Fatal(err)
+	}
+
+	return s, srv, c
+}
+
+func TestRPCRoundTrip(t *testing.T) {
+	s, _, c := startRPC(t, scheduler.New(64, 4))
+	defer s.Close()
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	addr := s.Addr().String()
+
+	got, err := c.Call(ctx, addr, "echo", []byte("hello"))
+	if err != nil || string(got) != "hello" {
+		t.Fatalf("echo returned %q, %v", got, err)
+	}
+
+	if _, err = c.Call(ctx, addr, "fail", nil); err != rpc.ServerError("failed") {
+		t.Fatalf("fail returned %v", err)
+	}
+
+	if _, err = c.Call(ctx, addr, "missing", nil); err == nil {
+		t.Fatal("calling a missing method succeeded")
+	}
+}
+
+func TestRPCConcurrentCalls(t *testing.T) {
+	s, srv, c := startRPC(t, scheduler.New(256, 16))
+	defer s.Close()
+	defer c.Close()
+
+	// Later calls overtake earlier ones.
+	srv.Register("slow", func(conn *tcp.Conn, payload []byte) ([]byte, error) {
+		time.Sleep(time.Duration(payload[0]%8) * time.Millisecond)
+		return payload, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			payload := []byte(fmt.Sprintf("%c call %d", byte(i), i))
+
+			got, err := c.Call(ctx, s.Addr().String(), "slow", payload)
+			if err != nil {
+				errs <- err
+			} else if !bytes.Equal(got, payload) {
+				errs <- fmt.Errorf("call %d got %q", i, got)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestRPCConnectionClosed(t *testing.T) {
+	s, srv, c := startRPC(t, scheduler.New(64, 4))
+	defer s.Close()
+	defer c.Close()
+
+	srv.Register("drop", func(conn *tcp.Conn, payload []byte) ([]byte, error) {
+		conn.Close()
+		return nil, nil
+	})
+
+	addr := s.Addr().String()
+
+	// No deadline, the call has to fail by itself.
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Call(context.Background(), addr, "drop", nil)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != rpc.ErrShutdown {
+			t.Fatalf("call returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("call still pending after the connection closed")
+	}
+
+	// The pool reconnects.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for {
+		got, err := c.Call(ctx, addr, "echo", []byte("again"))
+		if err == nil {
+			if string(got) != "again" {
+				t.Fatalf("echo returned %q", got)
+			}
+			break
+		}
+
+		if ctx.Err() != nil {
+			t.Fatalf("no reconnection: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRPCBusy(t *testing.T) {
+	// One worker and room for one queued call.
+	s, srv, c := startRPC(t, scheduler.New(1, 1))
+	defer s.Close()
+	defer c.Close()
+
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+
+	srv.Register("block", func(conn *tcp.Conn, payload []byte) ([]byte, error) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		return payload, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	addr := s.Addr().String()
+	errs := make(chan error, 8)
+
+	go func() {
+		_, err := c.Call(ctx, addr, "block", nil)
+		errs <- err
+	}()
+	<-started
+
+	for i := 0; i < 7; i++ {
+		go func() {
+			_, err := c.Call(ctx, addr, "block", nil)
+			errs <- err
+		}()
+	}
+
+	// Reads go on while the calls pool is full.
+	busy := 0
+	for busy == 0 {
+		select {
+		case err := <-errs:
+			if err != rpc.ErrServerBusy {
+				t.Fatalf("call returned %v", err)
+			}
+			busy++
+		case <-ctx.Done():
+			t.Fatal("no call was refused")
+		}
+	}
+
+	close(release)
+
+	for i := busy; i < 8; i++ {
+		if err := <-errs; err != nil && err != rpc.ErrServerBusy {
+			t.Fatal(err)
+		}
+	}
+}
+
+// unresponsive returns the address of a listener whose accept queue is
+// full, connecting to it hangs until the dial gives up.
+func unresponsive(t *testing.T) (string, func()) {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sa := &unix.SockaddrInet4{Addr: [4]byte{127, 0, 0, 1}}
+	if err = unix.Bind(fd, sa); err == nil {
+		err = unix.Listen(fd, 0)
+	}
+	if err != nil {
+		unix.Close(fd)
+		t.Fatal(err)
+	}
+
+	bound, _ := unix.Getsockname(fd)
+	addr := "127.0.0.1:" + strconv.Itoa(bound.(*unix.SockaddrInet4).Port)
+
+	// Never accepted, the queue fills up and later handshakes are dropped.
+	var queued []net.Conn
+	for {
+		c, err := net.DialTimeout("tcp", addr, 200*time.Millisecond)
+		if err != nil {
+			break
+		}
+		queued = append(queued, c)
+
+		if len(queued) > 16 {
+			closeConns(queued)
+			unix.Close(fd)
+			t.Skip("the accept queue doesn't fill up")
+		}
+	}
+
+	return addr, func() {
+		closeConns(queued)
+		unix.Close(fd)
+	}
+}
+
+func closeConns(conns []net.Conn) {
+	for _, c := range conns {
+		c.Close()
+	}
+}
+
+func TestRPCDialDeadline(t *testing.T) {
+	addr, done := unresponsive(t)
+	defer done()
+
+	c, err := rpc.NewClient(&tcp.ClientConfig{DialTimeout: 10 * time.Second}, scheduler.New(4, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err = c.Call(ctx, addr, "echo", nil); err == nil {
+		t.Fatal("call succeeded")
+	}
+
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("call returned after %v, past its deadline", d)
+	}
+
+	// Done before dialing.
+	<-ctx.Done()
+	if _, err = c.Call(ctx, addr, "echo", nil); err != context.DeadlineExceeded {
+		t.Fatalf("call returned %v", err)
+	}
+}
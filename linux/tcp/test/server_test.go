@@ -0,0 +1,111 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package test
+
+import (
+	"io"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+	"github.com/fengyfei/nuts/linux/tcp/tcptest"
+	"github.com/fengyfei/nuts/scheduler"
+)
+
+// settled waits until the process is back to at most goroutines and fds.
+func settled(t *testing.T, goroutines, fds int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > goroutines || descriptors() > fds {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines and %d descriptors, %d and %d before",
+				runtime.NumGoroutine(), descriptors(), goroutines, fds)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCloseReleasesPollers(t *testing.T) {
+	pool := scheduler.New(64, 4)
+	goroutines, fds := runtime.NumGoroutine(), descriptors()
+
+	for i := 0; i < 20; i++ {
+		s, err := tcp.StartServer(&tcp.Config{Address: "127.0.0.1:0", Reactors: 2}, tcptest.Echo{}, pool)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Close()
+
+		// Failing to start releases what was set up already.
+		_, err = tcp.StartServer(&tcp.Config{
+			Listeners: []tcp.Listener{
+				{Name: "first", Address: "127.0.0.1:0"},
+				{Name: "second", Address: "256.0.0.1:0"},
+			},
+		}, tcptest.Echo{}, pool)
+		if err == nil {
+			t.Fatal("started on an invalid address")
+		}
+	}
+
+	settled(t, goroutines, fds)
+}
+
+func TestClosePollsUntilDrained(t *testing.T) {
+	pool := scheduler.New(64, 4)
+	goroutines, fds := runtime.NumGoroutine(), descriptors()
+
+	s, err := tcp.StartServer(&tcp.Config{Address: "127.0.0.1:0"}, tcptest.Echo{}, pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Established connections are still served once closed.
+	s.Close()
+
+	b := make([]byte, 4)
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	conn.Write([]byte("ping"))
+
+	if _, err = io.ReadFull(conn, b); err != nil || string(b) != "ping" {
+		t.Fatalf("read %q, %v", b, err)
+	}
+
+	conn.Close()
+	settled(t, goroutines, fds)
+}
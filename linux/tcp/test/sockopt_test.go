@@ -0,0 +1,180 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+	"github.com/fengyfei/nuts/linux/tcp/tcptest"
+	"golang.org/x/sys/unix"
+)
+
+// options answers each line with the options of the accepted socket.
+type options struct {
+	tcptest.Echo
+}
+
+func (options) OnReadMessage(conn net.Conn) error {
+	b := make([]byte, 64)
+
+	if _, err := conn.Read(b); err != nil {
+		return err
+	}
+
+	rc, err := conn.(*tcp.Conn).Conn.(syscall.Conn).SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var answer string
+	rc.Control(func(fd uintptr) {
+		get := func(level, name int) int {
+			v, _ := unix.GetsockoptInt(int(fd), level, name)
+			return v
+		}
+
+		l, _ := unix.GetsockoptLinger(int(fd), unix.SOL_SOCKET, unix.SO_LINGER)
+		answer = fmt.Sprintf("nodelay=%d keepalive=%d idle=%d interval=%d count=%d rcvbuf=%d sndbuf=%d linger=%d/%d",
+			get(unix.IPPROTO_TCP, unix.TCP_NODELAY),
+			get(unix.SOL_SOCKET, unix.SO_KEEPALIVE),
+			get(unix.IPPROTO_TCP, unix.TCP_KEEPIDLE),
+			get(unix.IPPROTO_TCP, unix.TCP_KEEPINTVL),
+			get(unix.IPPROTO_TCP, unix.TCP_KEEPCNT),
+			get(unix.SOL_SOCKET, unix.SO_RCVBUF),
+			get(unix.SOL_SOCKET, unix.SO_SNDBUF),
+			l.Onoff, l.Linger)
+	})
+
+	_, err = conn.Write([]byte(answer + "\n"))
+	return err
+}
+
+func TestSocketOptions(t *testing.T) {
+	s := tcptest.NewServerConfig(&tcp.Config{
+		Socket: tcp.SocketOptions{
+			Nagle:             true,
+			KeepAlive:         30 * time.Second,
+			KeepAliveInterval: 5 * time.Second,
+			KeepAliveCount:    3,
+			ReadBuffer:        64 << 10,
+			WriteBuffer:       64 << 10,
+			Linger:            2 * time.Second,
+			FastOpen:          16,
+			Backlog:           64,
+		},
+	}, options{})
+	defer s.Close()
+
+	// The kernel doubles buffer sizes to account for its bookkeeping.
+	want := fmt.Sprintf("nodelay=0 keepalive=1 idle=30 interval=5 count=3 rcvbuf=%d sndbuf=%d linger=1/2", 128<<10, 128<<10)
+	if got := ask(t, "tcp", s.Addr); got != want {
+		t.Fatalf("options %q, want %q", got, want)
+	}
+
+	// Negative values turn keep-alive off and reset on close.
+	reset := tcptest.NewServerConfig(&tcp.Config{
+		Socket: tcp.SocketOptions{KeepAlive: -1, Linger: -1},
+	}, options{})
+	defer reset.Close()
+
+	got := ask(t, "tcp", reset.Addr)
+	if !strings.Contains(got, " keepalive=0 ") || !strings.HasSuffix(got, " linger=1/0") {
+		t.Fatalf("options %q", got)
+	}
+}
+
+func TestDeferAccept(t *testing.T) {
+	s := tcptest.NewServerConfig(&tcp.Config{
+		Socket: tcp.SocketOptions{DeferAccept: 5 * time.Second},
+	}, nil)
+	defer s.Close()
+
+	c := s.Dial(t)
+	defer c.Close()
+
+	// The connection is accepted once data arrives.
+	time.Sleep(200 * time.Millisecond)
+	if n := total(s.Stats()).Accepted; n != 0 {
+		t.Fatalf("%d connections accepted before any data", n)
+	}
+
+	c.SendString("ping").ExpectString("ping")
+}
+
+func TestInvalidSocketOptions(t *testing.T) {
+	cases := []struct {
+		network string
+		socket  tcp.SocketOptions
+		option  string
+	}{
+		{"tcp", tcp.SocketOptions{ReadBuffer: -1}, "ReadBuffer"},
+		{"tcp", tcp.SocketOptions{Backlog: -1}, "Backlog"},
+		{"tcp", tcp.SocketOptions{KeepAlive: time.Millisecond}, "KeepAlive"},
+		{"tcp", tcp.SocketOptions{KeepAliveCount: -1}, "KeepAliveCount"},
+		{"tcp", tcp.SocketOptions{Linger: 500 * time.Millisecond}, "Linger"},
+		{"tcp", tcp.SocketOptions{DeferAccept: -time.Second}, "DeferAccept"},
+		{"unix", tcp.SocketOptions{Nagle: true}, "Nagle"},
+		{"unix", tcp.SocketOptions{KeepAliveCount: 3}, "KeepAlive"},
+		{"unix", tcp.SocketOptions{FastOpen: 16}, "FastOpen"},
+		{"unix", tcp.SocketOptions{DeferAccept: time.Second}, "DeferAccept"},
+	}
+
+	for _, c := range cases {
+		_, err := tcp.StartServer(&tcp.Config{
+			Network: c.network,
+			Address: "@nuts-invalid-options",
+			Socket:  c.socket,
+		}, tcptest.Echo{}, nil)
+
+		var oe *tcp.OptionError
+		if !errors.As(err, &oe) || oe.Option != c.option {
+			t.Fatalf("%s %+v: %v", c.network, c.socket, err)
+		}
+	}
+
+	// Listeners are checked against their own options.
+	_, err := tcp.StartServer(&tcp.Config{
+		Listeners: []tcp.Listener{
+			{Name: "local", Network: "unix", Address: "@nuts-invalid-options", Socket: &tcp.SocketOptions{Nagle: true}},
+		},
+	}, tcptest.Echo{}, nil)
+
+	var oe *tcp.OptionError
+	if !errors.As(err, &oe) || oe.Option != "Nagle" {
+		t.Fatalf("listener options: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+	"github.com/fengyfei/nuts/linux/tcp/tcptest"
+)
+
+func total(stats []tcp.ReactorStats) tcp.ReactorStats {
+	var sum tcp.ReactorStats
+
+	for _, st := range stats {
+		sum.Accepted += st.Accepted
+		sum.Active += st.Active
+		sum.Reads += st.Reads
+		sum.Limited += st.Limited
+	}
+
+	return sum
+}
+
+func TestStats(t *testing.T) {
+	const conns = 16
+
+	s := tcptest.NewServerConfig(&tcp.Config{Reactors: 2}, nil)
+	defer s.Close()
+
+	stats := s.Stats()
+	if len(stats) != 2 || stats[0].Reactor != 0 || stats[1].Reactor != 1 {
+		t.Fatalf("stats of %+v", stats)
+	}
+
+	var clients []*tcptest.Client
+	for i := 0; i < conns; i++ {
+		c := s.Dial(t)
+		c.SendString("ping").ExpectString("ping")
+		clients = append(clients, c)
+	}
+
+	// Each reactor counts the connections of its own listener.
+	stats = s.Stats()
+	for _, st := range stats {
+		if st.Active != int64(st.Accepted) {
+			t.Fatalf("reactor %d: %d accepted, %d active", st.Reactor, st.Accepted, st.Active)
+		}
+	}
+
+	sum := total(stats)
+	if sum.Accepted != conns || sum.Active != conns || sum.Reads < conns || sum.Limited != 0 {
+		t.Fatalf("totals %+v", sum)
+	}
+
+	for _, c := range clients {
+		c.Close()
+	}
+
+	if err := s.CheckLeaks(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if sum = total(s.Stats()); sum.Accepted != conns || sum.Active != 0 {
+		t.Fatalf("totals %+v after closing", sum)
+	}
+}
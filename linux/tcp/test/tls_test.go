@@ -0,0 +1,363 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+	"github.com/fengyfei/nuts/linux/tcp/tcptest"
+)
+
+// authority issues certificates for the tests.
+type authority struct {
+	cert   *x509.Certificate
+	key    *ecdsa.PrivateKey
+	pool   *x509.CertPool
+	serial int64
+}
+
+func newAuthority(t *testing.T) *authority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test authority"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, _ := x509.ParseCertificate(der)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return &authority{cert: cert, key: key, pool: pool, serial: 1}
+}
+
+// issue returns a pair for cn, valid for names as well.
+func (a *authority) issue(t *testing.T, cn string, names ...string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a.serial++
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(a.serial),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// write saves a pair as PEM files named after base in dir.
+func write(t *testing.T, dir, base string, cert tls.Certificate) tcp.CertFile {
+	key, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := tcp.CertFile{
+		CertFile: filepath.Join(dir, base+".crt"),
+		KeyFile:  filepath.Join(dir, base+".key"),
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: key})
+
+	if err = os.WriteFile(f.CertFile, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.WriteFile(f.KeyFile, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return f
+}
+
+// served returns the common name of the certificate the server presents.
+func served(t *testing.T, addr string, config *tls.Config) string {
+	conn, err := tls.Dial("tcp", addr, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
+
+func TestTLSHandshake(t *testing.T) {
+	ca := newAuthority(t)
+
+	s := tcptest.NewServerConfig(&tcp.Config{
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{ca.issue(t, "localhost", "localhost")}},
+	}, nil)
+	defer s.Close()
+
+	conn, err := tls.Dial("tcp", s.Addr, &tls.Config{ServerName: "localhost", RootCAs: ca.pool})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := []byte("over tls")
+	conn.Write(msg)
+
+	got := make([]byte, len(msg))
+	if _, err = io.ReadFull(conn, got); err != nil || string(got) != string(msg) {
+		t.Fatalf("read %q: %v", got, err)
+	}
+	conn.Close()
+
+	// A client that isn't speaking TLS never reaches the handler.
+	plain := s.Dial(t)
+	plain.SendString("hello\r\n\r\n").ExpectEOF()
+	plain.Close()
+
+	if err = s.CheckLeaks(time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCertStore(t *testing.T) {
+	ca := newAuthority(t)
+	dir := t.TempDir()
+
+	first := write(t, dir, "first", ca.issue(t, "first", "first.test"))
+	wildcard := write(t, dir, "wildcard", ca.issue(t, "wildcard", "*.example.test"))
+	exact := write(t, dir, "exact", ca.issue(t, "exact", "www.example.test"))
+
+	store, err := tcp.NewCertStore(first, wildcard, exact)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := tcptest.NewServerConfig(&tcp.Config{
+		TLSConfig: &tls.Config{GetCertificate: store.GetCertificate},
+	}, nil)
+	defer s.Close()
+
+	for name, want := range map[string]string{
+		"first.test":       "first",
+		"www.example.test": "exact",
+		"api.example.test": "wildcard",
+		"unknown.test":     "first",
+	} {
+		config := &tls.Config{ServerName: name, RootCAs: ca.pool}
+
+		// The fallback isn't valid for the name, only check what's served.
+		if name == "unknown.test" {
+			config.InsecureSkipVerify = true
+		}
+
+		if cn := served(t, s.Addr, config); cn != want {
+			t.Fatalf("%s: served %s, want %s", name, cn, want)
+		}
+	}
+
+	// Reloading picks up the new pair.
+	write(t, dir, "first", ca.issue(t, "renewed", "first.test"))
+	if err = store.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if cn := served(t, s.Addr, &tls.Config{ServerName: "first.test", RootCAs: ca.pool}); cn != "renewed" {
+		t.Fatalf("served %s after reload", cn)
+	}
+
+	// A broken pair is refused, the current ones are kept.
+	if err = os.WriteFile(first.KeyFile, []byte("garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = store.Reload(); err == nil {
+		t.Fatal("reloading a broken pair succeeded")
+	}
+
+	if cn := served(t, s.Addr, &tls.Config{ServerName: "first.test", RootCAs: ca.pool}); cn != "renewed" {
+		t.Fatalf("served %s after a failed reload", cn)
+	}
+}
+
+// identifying answers each message with the common name of the verified
+// client certificate.
+type identifying struct {
+	tcptest.Echo
+}
+
+func (identifying) OnReadMessage(conn net.Conn) error {
+	b := make([]byte, 64)
+
+	if _, err := conn.Read(b); err != nil {
+		return err
+	}
+
+	name := "none"
+	if cert := conn.(*tcp.Conn).PeerCertificate(); cert != nil {
+		name = cert.Subject.CommonName
+	}
+
+	_, err := conn.Write([]byte(name + "\n"))
+	return err
+}
+
+func TestPeerCertificate(t *testing.T) {
+	ca := newAuthority(t)
+	server := ca.issue(t, "localhost", "localhost")
+	client := ca.issue(t, "client")
+
+	for auth, want := range map[tls.ClientAuthType]string{
+		tls.RequireAndVerifyClientCert: "client",
+		tls.RequestClientCert:          "none",
+	} {
+		s := tcptest.NewServerConfig(&tcp.Config{
+			TLSConfig: &tls.Config{
+				Certificates: []tls.Certificate{server},
+				ClientAuth:   auth,
+				ClientCAs:    ca.pool,
+			},
+		}, identifying{})
+
+		conn, err := tls.Dial("tcp", s.Addr, &tls.Config{
+			ServerName:   "localhost",
+			RootCAs:      ca.pool,
+			Certificates: []tls.Certificate{client},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		conn.Write([]byte("?"))
+
+		got := make([]byte, len(want)+1)
+		if _, err = io.ReadFull(conn, got); err != nil || string(got) != want+"\n" {
+			t.Fatalf("%v: read %q: %v", auth, got, err)
+		}
+
+		conn.Close()
+		s.Close()
+	}
+}
+
+// deadlined sets a read deadline in the past on "set", and reports reads
+// that time out.
+type deadlined struct {
+	tcptest.Echo
+}
+
+func (deadlined) OnReadMessage(conn net.Conn) error {
+	b := make([]byte, 64)
+
+	n, err := conn.Read(b)
+
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		conn.SetReadDeadline(time.Time{})
+		_, err = conn.Write([]byte("timeout"))
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if string(b[:n]) == "set" {
+		conn.SetReadDeadline(time.Now().Add(-time.Second))
+	}
+
+	_, err = conn.Write(b[:n])
+	return err
+}
+
+func TestTLSReadDeadline(t *testing.T) {
+	ca := newAuthority(t)
+
+	s := tcptest.NewServerConfig(&tcp.Config{
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{ca.issue(t, "localhost", "localhost")}},
+	}, deadlined{})
+	defer s.Close()
+
+	conn, err := tls.Dial("tcp", s.Addr, &tls.Config{ServerName: "localhost", RootCAs: ca.pool})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// The server polling for buffered data keeps the handler's deadline.
+	for _, step := range []struct{ send, want string }{
+		{"set", "set"},
+		{"read", "timeoutread"},
+	} {
+		conn.Write([]byte(step.send))
+
+		got := make([]byte, len(step.want))
+		if _, err = io.ReadFull(conn, got); err != nil || string(got) != step.want {
+			t.Fatalf("sent %q, read %q: %v", step.send, got, err)
+		}
+	}
+}
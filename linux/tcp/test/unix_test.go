@@ -0,0 +1,158 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package test
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+	"github.com/fengyfei/nuts/linux/tcp/tcptest"
+)
+
+// credentials answers each line with the credentials of the peer, or the
+// error getting them.
+type credentials struct {
+	tcptest.Echo
+}
+
+func (credentials) OnReadMessage(conn net.Conn) error {
+	b := make([]byte, 64)
+
+	if _, err := conn.Read(b); err != nil {
+		return err
+	}
+
+	answer := ""
+	if cred, err := conn.(*tcp.Conn).PeerCredentials(); err != nil {
+		answer = err.Error()
+	} else {
+		answer = fmt.Sprintf("%d %d %d", cred.PID, cred.UID, cred.GID)
+	}
+
+	_, err := conn.Write([]byte(answer + "\n"))
+	return err
+}
+
+func TestUnixSocket(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "echo.sock")
+
+	s := tcptest.NewServerConfig(&tcp.Config{Network: "unix", Address: sock}, nil)
+	defer s.Close()
+
+	if s.Addr != sock {
+		t.Fatalf("listening on %s", s.Addr)
+	}
+
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn.Write([]byte("ping"))
+
+	b := make([]byte, 4)
+	if _, err = conn.Read(b); err != nil || string(b) != "ping" {
+		t.Fatalf("read %q, %v", b, err)
+	}
+	conn.Close()
+
+	// Closing removes the socket file.
+	s.Close()
+	if _, err = os.Stat(sock); !os.IsNotExist(err) {
+		t.Fatalf("socket file left: %v", err)
+	}
+}
+
+func TestAbstractSocket(t *testing.T) {
+	name := fmt.Sprintf("@nuts-test-%d", os.Getpid())
+
+	s := tcptest.NewServerConfig(&tcp.Config{Network: "unix", Address: name}, nil)
+	defer s.Close()
+
+	if s.Addr != name {
+		t.Fatalf("listening on %s", s.Addr)
+	}
+
+	conn, err := net.Dial("unix", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte("ping"))
+
+	b := make([]byte, 4)
+	if _, err = conn.Read(b); err != nil || string(b) != "ping" {
+		t.Fatalf("read %q, %v", b, err)
+	}
+
+	// No file backs the socket.
+	if _, err = os.Stat(name[1:]); !os.IsNotExist(err) {
+		t.Fatalf("stat %s: %v", name[1:], err)
+	}
+}
+
+func TestUnixReusePort(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "echo.sock")
+
+	_, err := tcp.StartServer(&tcp.Config{
+		Network:  "unix",
+		Address:  sock,
+		Reactors: 2,
+	}, tcptest.Echo{}, nil)
+	if err != tcp.ErrReusePortNetwork {
+		t.Fatalf("started with %v", err)
+	}
+}
+
+func TestPeerCredentials(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "cred.sock")
+
+	s := tcptest.NewServerConfig(&tcp.Config{
+		Listeners: []tcp.Listener{
+			{Name: "tcp", Address: "127.0.0.1:0"},
+			{Name: "unix", Network: "unix", Address: sock},
+		},
+	}, credentials{})
+	defer s.Close()
+
+	want := fmt.Sprintf("%d %d %d", os.Getpid(), os.Getuid(), os.Getgid())
+	if got := ask(t, "unix", sock); got != want {
+		t.Fatalf("credentials %q, want %q", got, want)
+	}
+
+	if got := ask(t, "tcp", s.Addr); got != tcp.ErrNetwork.Error() {
+		t.Fatalf("credentials of a TCP peer: %q", got)
+	}
+}
@@ -0,0 +1,295 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+	"github.com/fengyfei/nuts/linux/tcp/ws"
+	"github.com/fengyfei/nuts/scheduler"
+)
+
+// echo sends every message back and reports closes, readErrors counts
+// the read errors of the server.
+type echo struct {
+	closed     chan int
+	readErrors int32
+}
+
+func (e *echo) OnOpen(c *ws.Conn) {}
+
+func (e *echo) OnMessage(c *ws.Conn, t ws.MessageType, data []byte) {
+	c.WriteMessage(t, data)
+}
+
+func (e *echo) OnClose(c *ws.Conn, code int, reason string) {
+	e.closed <- code
+}
+
+// wsClient is a minimal client, masking what it sends as browsers do.
+type wsClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+	resp *http.Response
+}
+
+func dialWS(t *testing.T, addr string, extensions string) *wsClient {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	key := make([]byte, 16)
+	rand.Read(key)
+	k := base64.StdEncoding.EncodeToString(key)
+
+	req := "GET /chat HTTP/1.1\r\nHost: " + addr + "\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + k + "\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Protocol: v2, v1\r\n"
+	if extensions != "" {
+		req += "Sec-WebSocket-Extensions: " + extensions + "\r\n"
+	}
+
+	if _, err = conn.Write([]byte(req + "\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &wsClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+	if c.resp, err = http.ReadResponse(c.r, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status %d", c.resp.StatusCode)
+	}
+
+	h := sha1.Sum([]byte(k + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+	if got := c.resp.Header.Get("Sec-WebSocket-Accept"); got != base64.StdEncoding.EncodeToString(h[:]) {
+		t.Fatalf("accept key %q", got)
+	}
+
+	return c
+}
+
+func (c *wsClient) send(b0 byte, payload []byte) {
+	var mask [4]byte
+	rand.Read(mask[:])
+
+	frame := []byte{b0}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, 0x80|byte(n))
+	case n <= 0xffff:
+		frame = binary.BigEndian.AppendUint16(append(frame, 0x80|126), uint16(n))
+	default:
+		frame = binary.BigEndian.AppendUint64(append(frame, 0x80|127), uint64(n))
+	}
+	frame = append(frame, mask[:]...)
+
+	for i, b := range payload {
+		frame = append(frame, b^mask[i&3])
+	}
+
+	if _, err := c.conn.Write(frame); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *wsClient) recv() (b0 byte, payload []byte) {
+	var h [2]byte
+	if _, err := io.ReadFull(c.r, h[:]); err != nil {
+		c.t.Fatal(err)
+	}
+
+	n := uint64(h[1] & 0x7f)
+	switch n {
+	case 126:
+		var b [2]byte
+		io.ReadFull(c.r, b[:])
+		n = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		io.ReadFull(c.r, b[:])
+		n = binary.BigEndian.Uint64(b[:])
+	}
+
+	if h[1]&0x80 != 0 {
+		c.t.Fatal("server frames must not be masked")
+	}
+
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		c.t.Fatal(err)
+	}
+
+	return h[0], payload
+}
+
+func startWS(t *testing.T, conf *ws.Config) (*tcp.Server, *echo) {
+	e := &echo{closed: make(chan int, 1)}
+
+	s, err := tcp.StartServer(&tcp.Config{
+		Address: "127.0.0.1:0",
+		OnReadError: func(*tcp.Conn, error) {
+			atomic.AddInt32(&e.readErrors, 1)
+		},
+	}, ws.NewServer(conf, e), scheduler.New(8, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, e
+}
+
+func TestWebSocket(t *testing.T) {
+	s, e := startWS(t, &ws.Config{Path: "/chat", Subprotocols: []string{"v1"}})
+	defer s.Close()
+
+	c := dialWS(t, s.Addr().String(), "")
+	if p := c.resp.Header.Get("Sec-WebSocket-Protocol"); p != "v1" {
+		t.Fatalf("subprotocol %q", p)
+	}
+
+	c.send(0x81, []byte("hello"))
+	if b0, p := c.recv(); b0 != 0x81 || string(p) != "hello" {
+		t.Fatalf("echo %x %q", b0, p)
+	}
+
+	// A fragmented binary message with a ping in the middle.
+	big := bytes.Repeat([]byte("0123456789"), 10000)
+	c.send(0x02, big[:50000])
+	c.send(0x89, []byte("ping"))
+	c.send(0x80, big[50000:])
+
+	if b0, p := c.recv(); b0 != 0x8a || string(p) != "ping" {
+		t.Fatalf("pong %x %q", b0, p)
+	}
+
+	if b0, p := c.recv(); b0 != 0x82 || !bytes.Equal(p, big) {
+		t.Fatalf("fragmented echo %x, %d bytes", b0, len(p))
+	}
+
+	// Closing handshake started by the client.
+	c.send(0x88, []byte{0x03, 0xe8})
+	if b0, p := c.recv(); b0 != 0x88 || binary.BigEndian.Uint16(p) != ws.CloseNormal {
+		t.Fatalf("close %x %v", b0, p)
+	}
+
+	if _, err := c.r.ReadByte(); err != io.EOF {
+		t.Fatalf("connection not closed: %v", err)
+	}
+
+	if code := <-e.closed; code != ws.CloseNormal {
+		t.Fatalf("OnClose code %d", code)
+	}
+
+	// A clean close is no read error.
+	if n := atomic.LoadInt32(&e.readErrors); n != 0 {
+		t.Fatalf("%d read errors", n)
+	}
+}
+
+func TestWebSocketDeflate(t *testing.T) {
+	s, e := startWS(t, &ws.Config{Compression: true})
+	defer s.Close()
+
+	c := dialWS(t, s.Addr().String(), "permessage-deflate; client_max_window_bits")
+	if ext := c.resp.Header.Get("Sec-WebSocket-Extensions"); !strings.HasPrefix(ext, "permessage-deflate") {
+		t.Fatalf("extensions %q", ext)
+	}
+
+	msg := strings.Repeat("compress me please, ", 100)
+
+	var b bytes.Buffer
+	w, _ := flate.NewWriter(&b, flate.BestCompression)
+	w.Write([]byte(msg))
+	w.Flush()
+
+	c.send(0xc1, bytes.TrimSuffix(b.Bytes(), []byte{0, 0, 0xff, 0xff}))
+
+	b0, p := c.recv()
+	if b0 != 0xc1 {
+		t.Fatalf("reply not compressed: %x", b0)
+	}
+
+	r := flate.NewReader(io.MultiReader(bytes.NewReader(p), strings.NewReader("\x00\x00\xff\xff\x01\x00\x00\xff\xff")))
+	got, err := io.ReadAll(r)
+	if err != nil || string(got) != msg {
+		t.Fatalf("inflated %q %v", got, err)
+	}
+
+	// Invalid UTF-8 in a text message fails the connection.
+	c.send(0x81, []byte{0xff, 0xfe})
+	if b0, p := c.recv(); b0 != 0x88 || binary.BigEndian.Uint16(p) != ws.CloseInvalidPayload {
+		t.Fatalf("close %x %v", b0, p)
+	}
+
+	if code := <-e.closed; code != ws.CloseInvalidPayload {
+		t.Fatalf("OnClose code %d", code)
+	}
+}
+
+func TestWebSocketRefused(t *testing.T) {
+	s, _ := startWS(t, &ws.Config{Path: "/chat"})
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte("GET /other HTTP/1.1\r\nHost: x\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,221 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package tcp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHandshakeTimeout = 10 * time.Second
+)
+
+var (
+	// ErrNoCertificate means a CertStore was created without any pair.
+	ErrNoCertificate = errors.New("no certificate")
+)
+
+// handshake performs the TLS handshake on its own goroutine. Go's runtime
+// parks it on the network poller while waiting for the peer, so neither the
+// event loop nor a scheduler worker is held by slow or malicious clients.
+func (s *Server) handshake(l *listener, raw net.Conn, hdr *ProxyHeader) {
+	conn := tls.Server(raw, s.conf.TLSConfig)
+
+	raw.SetDeadline(time.Now().Add(s.conf.handshakeTimeout()))
+	if err := conn.Handshake(); err != nil {
+		s.reject(l, raw, err)
+		return
+	}
+	raw.SetDeadline(time.Time{})
+
+	s.start(l, raw, conn, hdr)
+}
+
+// CertFile names a PEM encoded certificate and key pair on disk.
+type CertFile struct {
+	CertFile string
+	KeyFile  string
+}
+
+// CertStore holds certificates loaded from disk and selects one by SNI.
+// Plug GetCertificate into tls.Config; Reload or Watch replace the
+// certificates without restarting the server.
+type CertStore struct {
+	files []CertFile
+
+	mu       sync.RWMutex
+	names    map[string]*tls.Certificate
+	fallback *tls.Certificate
+	modified time.Time
+}
+
+// NewCertStore loads the pairs. The first one is served to clients that
+// don't send SNI or ask for an unknown name.
+func NewCertStore(files ...CertFile) (*CertStore, error) {
+	if len(files) == 0 {
+		return nil, ErrNoCertificate
+	}
+
+	store := &CertStore{
+		files: files,
+	}
+
+	if err := store.Reload(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Reload reads all pairs again, on error the current certificates are kept.
+func (store *CertStore) Reload() error {
+	var (
+		names    = make(map[string]*tls.Certificate)
+		fallback *tls.Certificate
+	)
+
+	modified, err := store.lastModified()
+	if err != nil {
+		return err
+	}
+
+	for _, f := range store.files {
+		cert, err := tls.LoadX509KeyPair(f.CertFile, f.KeyFile)
+		if err != nil {
+			return err
+		}
+
+		if cert.Leaf == nil {
+			if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+				return err
+			}
+		}
+
+		if fallback == nil {
+			fallback = &cert
+		}
+
+		for _, name := range cert.Leaf.DNSNames {
+			names[strings.ToLower(name)] = &cert
+		}
+
+		if len(cert.Leaf.DNSNames) == 0 && cert.Leaf.Subject.CommonName != "" {
+			names[strings.ToLower(cert.Leaf.Subject.CommonName)] = &cert
+		}
+	}
+
+	store.mu.Lock()
+	store.names = names
+	store.fallback = fallback
+	store.modified = modified
+	store.mu.Unlock()
+
+	return nil
+}
+
+// Watch reloads the certificates whenever one of the files changes, it
+// checks every interval until stop is closed. Reload errors are passed to
+// onError, which may be nil.
+func (store *CertStore) Watch(interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			modified, err := store.lastModified()
+			if err == nil {
+				store.mu.RLock()
+				changed := modified.After(store.modified)
+				store.mu.RUnlock()
+
+				if !changed {
+					continue
+				}
+
+				err = store.Reload()
+			}
+
+			if err != nil && onError != nil {
+				onError(err)
+			}
+
+		case <-stop:
+			return
+		}
+	}
+}
+
+// GetCertificate implements tls.Config.GetCertificate. An exact match wins
+// over a wildcard one.
+func (store *CertStore) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := strings.TrimSuffix(strings.ToLower(hello.ServerName), ".")
+
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	if cert, ok := store.names[name]; ok {
+		return cert, nil
+	}
+
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		if cert, ok := store.names["*"+name[i:]]; ok {
+			return cert, nil
+		}
+	}
+
+	return store.fallback, nil
+}
+
+func (store *CertStore) lastModified() (time.Time, error) {
+	var latest time.Time
+
+	for _, f := range store.files {
+		for _, name := range []string{f.CertFile, f.KeyFile} {
+			info, err := os.Stat(name)
+			if err != nil {
+				return latest, err
+			}
+
+			if info.ModTime().After(latest) {
+				latest = info.ModTime()
+			}
+		}
+	}
+
+	return latest, nil
+}
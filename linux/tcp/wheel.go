@@ -0,0 +1,146 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package tcp
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	wheelSlots = 512
+)
+
+// timingWheel is a hashed timing wheel shared by all connections of a
+// server, so that idle detection costs one ticker instead of one timer per
+// connection.
+type timingWheel struct {
+	mu      sync.Mutex
+	tick    time.Duration
+	slots   []map[*Conn]struct{}
+	current int
+	expire  func(*Conn)
+	done    chan struct{}
+}
+
+func newTimingWheel(tick time.Duration, expire func(*Conn)) *timingWheel {
+	w := &timingWheel{
+		tick:   tick,
+		slots:  make([]map[*Conn]struct{}, wheelSlots),
+		expire: expire,
+		done:   make(chan struct{}),
+	}
+
+	for i := range w.slots {
+		w.slots[i] = make(map[*Conn]struct{})
+	}
+
+	go w.run()
+
+	return w
+}
+
+func (w *timingWheel) run() {
+	ticker := time.NewTicker(w.tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.advance()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// advance moves the wheel one slot forward and expires due connections.
+func (w *timingWheel) advance() {
+	var expired []*Conn
+
+	w.mu.Lock()
+	w.current = (w.current + 1) % len(w.slots)
+	slot := w.slots[w.current]
+
+	for c := range slot {
+		if c.rounds > 0 {
+			c.rounds--
+			continue
+		}
+
+		delete(slot, c)
+		c.slot = -1
+		expired = append(expired, c)
+	}
+	w.mu.Unlock()
+
+	for _, c := range expired {
+		w.expire(c)
+	}
+}
+
+// schedule (re)arms c to expire after d, unless it was closed. Closing
+// sets the flag before removing c under the lock, so a connection closed
+// meanwhile is never left on the wheel.
+func (w *timingWheel) schedule(c *Conn, d time.Duration) {
+	ticks := int((d + w.tick - 1) / w.tick)
+	if ticks < 1 {
+		ticks = 1
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if c.isClosed() {
+		return
+	}
+
+	if c.slot >= 0 {
+		delete(w.slots[c.slot], c)
+	}
+
+	c.slot = (w.current + ticks) % len(w.slots)
+	c.rounds = (ticks - 1) / len(w.slots)
+	w.slots[c.slot][c] = struct{}{}
+}
+
+// remove disarms c.
+func (w *timingWheel) remove(c *Conn) {
+	w.mu.Lock()
+	if c.slot >= 0 {
+		delete(w.slots[c.slot], c)
+		c.slot = -1
+	}
+	w.mu.Unlock()
+}
+
+func (w *timingWheel) stop() {
+	close(w.done)
+}
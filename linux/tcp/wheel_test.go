@@ -0,0 +1,104 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package tcp
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// armed counts the connections on the wheel.
+func (w *timingWheel) armed() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := 0
+	for _, slot := range w.slots {
+		n += len(slot)
+	}
+
+	return n
+}
+
+func TestTimingWheel(t *testing.T) {
+	expired := make(chan *Conn, 4)
+
+	w := newTimingWheel(time.Millisecond, func(c *Conn) {
+		expired <- c
+	})
+	defer w.stop()
+
+	// Longer than a turn of the wheel.
+	long := &Conn{slot: -1}
+	short := &Conn{slot: -1}
+	start := time.Now()
+
+	w.schedule(long, 600*time.Millisecond)
+	w.schedule(short, 10*time.Millisecond)
+
+	if c := <-expired; c != short {
+		t.Fatal("the long timeout expired first")
+	}
+
+	if c := <-expired; c != long {
+		t.Fatal("unexpected connection expired")
+	} else if elapsed := time.Since(start); elapsed < 600*time.Millisecond {
+		t.Fatalf("expired after %v", elapsed)
+	}
+
+	// Re-arming replaces the previous timeout, removing disarms.
+	c := &Conn{slot: -1}
+	w.schedule(c, time.Hour)
+	w.schedule(c, time.Hour)
+	if n := w.armed(); n != 1 {
+		t.Fatalf("%d connections armed", n)
+	}
+
+	w.remove(c)
+	if n := w.armed(); n != 0 {
+		t.Fatalf("%d connections armed after remove", n)
+	}
+}
+
+func TestTimingWheelClosed(t *testing.T) {
+	w := newTimingWheel(time.Millisecond, func(*Conn) {})
+	defer w.stop()
+
+	// Closed after its idle check looked, before re-arming.
+	c := &Conn{slot: -1}
+	atomic.StoreInt32(&c.closed, 1)
+	w.remove(c)
+
+	w.schedule(c, time.Hour)
+	if n := w.armed(); n != 0 {
+		t.Fatalf("closed connection left on the wheel")
+	}
+}
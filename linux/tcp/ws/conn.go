@@ -0,0 +1,459 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package ws
+
+import (
+	"encoding/binary"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+)
+
+// MessageType is the type of a data message.
+type MessageType int
+
+// Types of data messages.
+const (
+	TextMessage   = MessageType(opText)
+	BinaryMessage = MessageType(opBinary)
+)
+
+// Close codes, see RFC 6455 section 7.4.
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseNoStatus        = 1005
+	CloseAbnormal        = 1006
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+)
+
+const (
+	stateHandshake int32 = iota
+	stateOpen
+)
+
+// Conn is an upgraded connection. Its write methods are safe for
+// concurrent use.
+type Conn struct {
+	// Set when a ping is waiting for an answer, accessed atomically.
+	pinged int32
+	state  int32
+
+	server      *Server
+	nc          *tcp.Conn
+	request     *http.Request
+	subprotocol string
+	compress    bool
+
+	// Only used by the read task.
+	buf        []byte
+	msgOp      byte
+	compressed bool
+	msg        []byte
+
+	wmu        sync.Mutex
+	closeSent  bool
+	closeTimer *time.Timer
+
+	// Status reported to OnClose, the first Close frame sent or received.
+	mu     sync.Mutex
+	code   int
+	reason string
+}
+
+func newConn(s *Server, nc *tcp.Conn) *Conn {
+	return &Conn{
+		server: s,
+		nc:     nc,
+		code:   CloseAbnormal,
+	}
+}
+
+// Request returns the upgrade request.
+func (c *Conn) Request() *http.Request {
+	return c.request
+}
+
+// Subprotocol returns the negotiated protocol, empty if none.
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// NetConn returns the underlying connection.
+func (c *Conn) NetConn() *tcp.Conn {
+	return c.nc
+}
+
+// RemoteAddr returns the address of the peer.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.nc.RemoteAddr()
+}
+
+// WriteMessage sends data as a single frame, compressed when negotiated.
+func (c *Conn) WriteMessage(t MessageType, data []byte) error {
+	rsv1 := false
+
+	if c.compress && len(data) >= minCompressSize {
+		b, err := compress(data)
+		if err != nil {
+			return err
+		}
+
+		data, rsv1 = b, true
+	}
+
+	return c.write(byte(t), rsv1, data)
+}
+
+// Ping sends a ping, the peer answers with a pong carrying the same data.
+func (c *Conn) Ping(data []byte) error {
+	if len(data) > maxControlPayload {
+		return errProtocol
+	}
+
+	return c.write(opPing, false, data)
+}
+
+// Close starts the closing handshake. The connection is closed once the
+// peer answered, or after Config.CloseTimeout.
+func (c *Conn) Close(code int, reason string) error {
+	if len(reason) > maxControlPayload-2 {
+		return ErrReasonTooLong
+	}
+
+	c.setStatus(code, reason)
+	if err := c.sendClose(code, reason); err != nil {
+		c.nc.Close()
+		return err
+	}
+
+	c.wmu.Lock()
+	if c.closeTimer == nil {
+		c.closeTimer = time.AfterFunc(c.server.closeTimeout(), func() {
+			c.nc.Close()
+		})
+	}
+	c.wmu.Unlock()
+
+	return nil
+}
+
+func (c *Conn) write(op byte, rsv1 bool, data []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if c.closeSent {
+		return ErrClosed
+	}
+
+	return c.writeFrame(op, rsv1, data)
+}
+
+// writeFrame needs wmu held. Small frames are copied to go out with a
+// single write, larger ones are sent with their header by writev.
+func (c *Conn) writeFrame(op byte, rsv1 bool, data []byte) error {
+	var hdr [maxHeaderSize]byte
+
+	b := appendHeader(hdr[:0], true, rsv1, op, len(data))
+	if len(data) <= minReadSpace {
+		_, err := c.nc.Write(append(b, data...))
+		return err
+	}
+
+	_, err := c.nc.Writev([][]byte{b, data})
+	return err
+}
+
+// sendClose sends a Close frame, unless one was already sent.
+func (c *Conn) sendClose(code int, reason string) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if c.closeSent {
+		return nil
+	}
+	c.closeSent = true
+
+	var payload []byte
+	if code != CloseNoStatus {
+		payload = binary.BigEndian.AppendUint16(payload, uint16(code))
+		payload = append(payload, reason...)
+	}
+
+	return c.writeFrame(opClose, false, payload)
+}
+
+func (c *Conn) stopCloseTimer() {
+	c.wmu.Lock()
+	if c.closeTimer != nil {
+		c.closeTimer.Stop()
+	}
+	c.wmu.Unlock()
+}
+
+func (c *Conn) closing() bool {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	return c.closeSent
+}
+
+func (c *Conn) setStatus(code int, reason string) {
+	c.mu.Lock()
+	if c.code == CloseAbnormal {
+		c.code, c.reason = code, reason
+	}
+	c.mu.Unlock()
+}
+
+func (c *Conn) closeStatus() (int, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.code, c.reason
+}
+
+func (c *Conn) isOpen() bool {
+	return atomic.LoadInt32(&c.state) == stateOpen
+}
+
+// read reads what's available and handles every complete frame.
+func (c *Conn) read() error {
+	if cap(c.buf)-len(c.buf) < minReadSpace {
+		buf := make([]byte, len(c.buf), 2*cap(c.buf)+minReadSpace)
+		copy(buf, c.buf)
+		c.buf = buf
+	}
+
+	n, err := c.nc.Read(c.buf[len(c.buf):cap(c.buf)])
+	c.buf = c.buf[:len(c.buf)+n]
+
+	if perr := c.process(); perr != nil {
+		return perr
+	}
+
+	return err
+}
+
+func (c *Conn) process() error {
+	if !c.isOpen() {
+		r, size, err := readRequest(c.buf, c.server.maxHeaderSize())
+		if err == nil && r == nil {
+			return nil
+		}
+
+		var resp []byte
+		if err == nil {
+			resp, err = c.server.upgrade(c, r)
+		}
+
+		if err != nil {
+			c.nc.Write(refusal(err))
+			return err
+		}
+
+		if _, err = c.nc.Write(resp); err != nil {
+			return err
+		}
+
+		c.request = r
+		c.buf = c.buf[:copy(c.buf, c.buf[size:])]
+		atomic.StoreInt32(&c.state, stateOpen)
+		c.server.handler.OnOpen(c)
+	}
+
+	off := 0
+	defer func() {
+		c.buf = c.buf[:copy(c.buf, c.buf[off:])]
+	}()
+
+	for {
+		h, n, err := parseHeader(c.buf[off:])
+		switch {
+		case err == errIncomplete:
+			return nil
+		case err != nil || !h.masked:
+			return c.fail(CloseProtocolError, "")
+		case h.length > c.server.maxMessageSize():
+			return c.fail(CloseMessageTooBig, "")
+		}
+
+		end := off + n + int(h.length)
+		if end > len(c.buf) {
+			return nil
+		}
+
+		payload := c.buf[off+n : end]
+		maskBytes(h.mask, payload)
+		off = end
+
+		atomic.StoreInt32(&c.pinged, 0)
+
+		if err = c.frame(h, payload); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Conn) frame(h header, payload []byte) error {
+	if isControl(h.op) {
+		if !h.fin || h.rsv1 || h.length > maxControlPayload {
+			return c.fail(CloseProtocolError, "")
+		}
+
+		switch h.op {
+		case opPing:
+			if err := c.write(opPong, false, payload); err != nil && err != ErrClosed {
+				return err
+			}
+		case opPong:
+			if ph, ok := c.server.handler.(PongHandler); ok {
+				ph.OnPong(c, payload)
+			}
+		case opClose:
+			return c.peerClosed(payload)
+		default:
+			return c.fail(CloseProtocolError, "")
+		}
+
+		return nil
+	}
+
+	switch h.op {
+	case opText, opBinary:
+		if c.msgOp != 0 || (h.rsv1 && !c.compress) {
+			return c.fail(CloseProtocolError, "")
+		}
+
+		if h.fin {
+			return c.message(h.op, h.rsv1, payload)
+		}
+
+		c.msgOp, c.compressed = h.op, h.rsv1
+		c.msg = append(c.msg[:0], payload...)
+	case opContinuation:
+		if c.msgOp == 0 || h.rsv1 {
+			return c.fail(CloseProtocolError, "")
+		}
+
+		if int64(len(c.msg)+len(payload)) > c.server.maxMessageSize() {
+			return c.fail(CloseMessageTooBig, "")
+		}
+
+		c.msg = append(c.msg, payload...)
+		if h.fin {
+			op := c.msgOp
+			c.msgOp = 0
+
+			return c.message(op, c.compressed, c.msg)
+		}
+	default:
+		return c.fail(CloseProtocolError, "")
+	}
+
+	return nil
+}
+
+func (c *Conn) message(op byte, compressed bool, data []byte) error {
+	if compressed {
+		var err error
+
+		if data, err = decompress(data, c.server.maxMessageSize()); err == errMessageTooBig {
+			return c.fail(CloseMessageTooBig, "")
+		} else if err != nil {
+			return c.fail(CloseInvalidPayload, "")
+		}
+	}
+
+	if op == opText && !utf8.Valid(data) {
+		return c.fail(CloseInvalidPayload, "")
+	}
+
+	// Messages arriving after Close was sent are dropped.
+	if !c.closing() {
+		c.server.handler.OnMessage(c, MessageType(op), data)
+	}
+
+	return nil
+}
+
+// peerClosed answers a Close frame, then the connection is closed.
+func (c *Conn) peerClosed(payload []byte) error {
+	code, reason := CloseNoStatus, ""
+
+	if len(payload) > 0 {
+		if len(payload) < 2 {
+			return c.fail(CloseProtocolError, "")
+		}
+
+		code, reason = int(binary.BigEndian.Uint16(payload)), string(payload[2:])
+		if !validCloseCode(code) {
+			return c.fail(CloseProtocolError, "")
+		}
+
+		if !utf8.ValidString(reason) {
+			return c.fail(CloseInvalidPayload, "")
+		}
+	}
+
+	c.setStatus(code, reason)
+	c.sendClose(code, "")
+
+	return errPeerClosed
+}
+
+// fail sends a Close frame with code, then the connection is closed.
+func (c *Conn) fail(code int, reason string) error {
+	c.setStatus(code, reason)
+	c.sendClose(code, reason)
+
+	return errProtocol
+}
+
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 3000 && code <= 4999:
+		return true
+	case code < 1000 || code > 1014:
+		return false
+	}
+
+	return code != 1004 && code != CloseNoStatus && code != CloseAbnormal
+}
@@ -0,0 +1,143 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package ws
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	deflateExtension = "permessage-deflate"
+
+	// Messages shorter than this are sent uncompressed.
+	minCompressSize = 64
+)
+
+// deflateTail ends a message with the sync flush marker stripped by the
+// sender, then an empty final block so the reader sees a clean EOF.
+const deflateTail = "\x00\x00\xff\xff\x01\x00\x00\xff\xff"
+
+var (
+	flateWriters sync.Pool
+	flateReaders sync.Pool
+)
+
+// negotiateDeflate accepts the first permessage-deflate offer the server
+// can honor. Both sides are asked not to keep their context between
+// messages, so compressors are pooled instead of held by connections.
+func negotiateDeflate(r *http.Request) (string, bool) {
+	for _, offer := range headerList(r.Header, "Sec-Websocket-Extensions") {
+		params := strings.Split(offer, ";")
+		if strings.TrimSpace(params[0]) != deflateExtension {
+			continue
+		}
+
+		if acceptDeflate(params[1:]) {
+			return deflateExtension + "; server_no_context_takeover; client_no_context_takeover", true
+		}
+	}
+
+	return "", false
+}
+
+func acceptDeflate(params []string) bool {
+	for _, p := range params {
+		name, value, _ := strings.Cut(strings.TrimSpace(p), "=")
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+
+		switch strings.TrimSpace(name) {
+		case "server_no_context_takeover", "client_no_context_takeover":
+		case "client_max_window_bits":
+			// Replying without it makes the client use 15 bits.
+		case "server_max_window_bits":
+			// compress/flate always uses a 32 KiB window.
+			if value != "15" {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func compress(data []byte) ([]byte, error) {
+	var b bytes.Buffer
+
+	w, _ := flateWriters.Get().(*flate.Writer)
+	if w == nil {
+		w, _ = flate.NewWriter(&b, flate.BestSpeed)
+	} else {
+		w.Reset(&b)
+	}
+	defer flateWriters.Put(w)
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+
+	// Strip the sync flush marker, see deflateTail.
+	return bytes.TrimSuffix(b.Bytes(), []byte(deflateTail[:4])), nil
+}
+
+// decompress inflates a message, failing with errMessageTooBig past limit
+// bytes.
+func decompress(data []byte, limit int64) ([]byte, error) {
+	src := io.MultiReader(bytes.NewReader(data), strings.NewReader(deflateTail))
+
+	r, _ := flateReaders.Get().(io.ReadCloser)
+	if r == nil {
+		r = flate.NewReader(src)
+	} else {
+		r.(flate.Resetter).Reset(src, nil)
+	}
+	defer flateReaders.Put(r)
+
+	out, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(out)) > limit {
+		return nil, errMessageTooBig
+	}
+
+	return out, nil
+}
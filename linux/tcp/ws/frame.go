@@ -0,0 +1,156 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package ws
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	opContinuation byte = 0x0
+	opText         byte = 0x1
+	opBinary       byte = 0x2
+	opClose        byte = 0x8
+	opPing         byte = 0x9
+	opPong         byte = 0xa
+)
+
+const (
+	finBit  = 0x80
+	rsv1Bit = 0x40
+	rsvBits = 0x70
+	maskBit = 0x80
+
+	maxHeaderSize     = 2 + 8 + 4
+	maxControlPayload = 125
+	minReadSpace      = 4096
+)
+
+var errIncomplete = errors.New("incomplete frame")
+
+// header is the fixed part of a frame.
+type header struct {
+	fin    bool
+	rsv1   bool
+	op     byte
+	masked bool
+	mask   [4]byte
+	length int64
+}
+
+// isControl tells Close, Ping and Pong frames apart from data frames.
+func isControl(op byte) bool {
+	return op&0x8 != 0
+}
+
+// parseHeader decodes the header at the start of b and returns its size.
+// errIncomplete means b doesn't hold all of it yet.
+func parseHeader(b []byte) (h header, n int, err error) {
+	if len(b) < 2 {
+		return h, 0, errIncomplete
+	}
+
+	h.fin = b[0]&finBit != 0
+	h.rsv1 = b[0]&rsv1Bit != 0
+	h.op = b[0] & 0x0f
+	h.masked = b[1]&maskBit != 0
+
+	if b[0]&rsvBits&^rsv1Bit != 0 {
+		return h, 0, errProtocol
+	}
+
+	n = 2
+	switch l := b[1] &^ maskBit; l {
+	case 126:
+		if len(b) < n+2 {
+			return h, 0, errIncomplete
+		}
+
+		h.length = int64(binary.BigEndian.Uint16(b[n:]))
+		n += 2
+	case 127:
+		if len(b) < n+8 {
+			return h, 0, errIncomplete
+		}
+
+		u := binary.BigEndian.Uint64(b[n:])
+		if u>>63 != 0 {
+			return h, 0, errProtocol
+		}
+
+		h.length = int64(u)
+		n += 8
+	default:
+		h.length = int64(l)
+	}
+
+	if h.masked {
+		if len(b) < n+4 {
+			return h, 0, errIncomplete
+		}
+
+		copy(h.mask[:], b[n:])
+		n += 4
+	}
+
+	return h, n, nil
+}
+
+// appendHeader encodes an unmasked header, as sent by servers.
+func appendHeader(b []byte, fin, rsv1 bool, op byte, length int) []byte {
+	b0 := op
+	if fin {
+		b0 |= finBit
+	}
+
+	if rsv1 {
+		b0 |= rsv1Bit
+	}
+
+	switch {
+	case length <= maxControlPayload:
+		return append(b, b0, byte(length))
+	case length <= 0xffff:
+		b = append(b, b0, 126)
+		return binary.BigEndian.AppendUint16(b, uint16(length))
+	}
+
+	b = append(b, b0, 127)
+	return binary.BigEndian.AppendUint64(b, uint64(length))
+}
+
+// maskBytes applies the masking key to a whole payload, which unmasks it
+// as well.
+func maskBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i&3]
+	}
+}
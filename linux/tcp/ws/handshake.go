@@ -0,0 +1,186 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package ws
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// handshakeError is an upgrade refused with an HTTP status.
+type handshakeError struct {
+	status int
+	reason string
+}
+
+func (e *handshakeError) Error() string {
+	return "websocket handshake: " + e.reason
+}
+
+// readRequest parses the upgrade request at the start of b, it returns the
+// size of the request, 0 if it isn't complete yet.
+func readRequest(b []byte, limit int) (*http.Request, int, error) {
+	end := bytes.Index(b, []byte("\r\n\r\n"))
+	if end < 0 {
+		if len(b) > limit {
+			return nil, 0, &handshakeError{http.StatusRequestHeaderFieldsTooLarge, "request too large"}
+		}
+
+		return nil, 0, nil
+	}
+
+	end += 4
+	if end > limit {
+		return nil, 0, &handshakeError{http.StatusRequestHeaderFieldsTooLarge, "request too large"}
+	}
+
+	r, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(b[:end])))
+	if err != nil {
+		return nil, 0, &handshakeError{http.StatusBadRequest, err.Error()}
+	}
+
+	return r, end, nil
+}
+
+// upgrade checks the request and returns the response switching protocols.
+func (s *Server) upgrade(c *Conn, r *http.Request) ([]byte, error) {
+	switch {
+	case r.Method != http.MethodGet:
+		return nil, &handshakeError{http.StatusMethodNotAllowed, "method not GET"}
+	case !r.ProtoAtLeast(1, 1):
+		return nil, &handshakeError{http.StatusBadRequest, "protocol older than HTTP/1.1"}
+	case !hasToken(r.Header, "Connection", "upgrade"):
+		return nil, &handshakeError{http.StatusBadRequest, "'upgrade' token not found in 'Connection' header"}
+	case !hasToken(r.Header, "Upgrade", "websocket"):
+		return nil, &handshakeError{http.StatusBadRequest, "'websocket' token not found in 'Upgrade' header"}
+	case r.Header.Get("Sec-Websocket-Version") != "13":
+		return nil, &handshakeError{http.StatusUpgradeRequired, "unsupported version"}
+	case s.conf.Path != "" && r.URL.Path != s.conf.Path:
+		return nil, &handshakeError{http.StatusNotFound, "unknown path " + r.URL.Path}
+	case s.conf.CheckOrigin != nil && !s.conf.CheckOrigin(r):
+		return nil, &handshakeError{http.StatusForbidden, "origin not allowed"}
+	}
+
+	key := r.Header.Get("Sec-Websocket-Key")
+	if k, err := base64.StdEncoding.DecodeString(key); err != nil || len(k) != 16 {
+		return nil, &handshakeError{http.StatusBadRequest, "invalid 'Sec-WebSocket-Key'"}
+	}
+
+	var b bytes.Buffer
+
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: ")
+	b.WriteString(acceptKey(key))
+	b.WriteString("\r\n")
+
+	if p := s.subprotocol(r); p != "" {
+		c.subprotocol = p
+		b.WriteString("Sec-WebSocket-Protocol: " + p + "\r\n")
+	}
+
+	if s.conf.Compression {
+		if ext, ok := negotiateDeflate(r); ok {
+			c.compress = true
+			b.WriteString("Sec-WebSocket-Extensions: " + ext + "\r\n")
+		}
+	}
+
+	b.WriteString("\r\n")
+
+	return b.Bytes(), nil
+}
+
+// subprotocol picks the first protocol offered by the client the server
+// speaks.
+func (s *Server) subprotocol(r *http.Request) string {
+	for _, p := range headerList(r.Header, "Sec-Websocket-Protocol") {
+		for _, q := range s.conf.Subprotocols {
+			if p == q {
+				return p
+			}
+		}
+	}
+
+	return ""
+}
+
+// refusal is the HTTP response rejecting an upgrade.
+func refusal(err error) []byte {
+	status := http.StatusBadRequest
+	if he, ok := err.(*handshakeError); ok {
+		status = he.status
+	}
+
+	resp := fmt.Sprintf("HTTP/1.1 %d %s\r\nConnection: close\r\nContent-Length: 0\r\n", status, http.StatusText(status))
+	if status == http.StatusUpgradeRequired {
+		resp += "Sec-WebSocket-Version: 13\r\n"
+	}
+
+	return []byte(resp + "\r\n")
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerList splits the comma separated values of a header, which may also
+// be repeated.
+func headerList(h http.Header, name string) []string {
+	var list []string
+
+	for _, v := range h.Values(name) {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+	}
+
+	return list
+}
+
+func hasToken(h http.Header, name, token string) bool {
+	for _, s := range headerList(h, name) {
+		if strings.EqualFold(s, token) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,221 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package ws
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fengyfei/nuts/linux/tcp"
+)
+
+const (
+	defaultMaxHeaderSize  = 8 << 10
+	defaultMaxMessageSize = 16 << 20
+	defaultCloseTimeout   = 5 * time.Second
+)
+
+var (
+	// ErrClosed means the closing handshake started, nothing can be sent
+	// anymore.
+	ErrClosed = errors.New("websocket closed")
+
+	// ErrReasonTooLong means a close reason doesn't fit in a control frame.
+	ErrReasonTooLong = errors.New("close reason too long")
+
+	errProtocol      = errors.New("websocket protocol error")
+	errMessageTooBig = errors.New("websocket message too big")
+	errPeerClosed    = errors.New("websocket closed by peer")
+)
+
+// Config is configuration for a WebSocket server.
+type Config struct {
+	// Path restricts upgrades to a request path, any is accepted by
+	// default.
+	Path string
+
+	// CheckOrigin refuses the upgrade when it returns false, nil accepts
+	// any origin.
+	CheckOrigin func(r *http.Request) bool
+
+	// Subprotocols lists the protocols the server speaks, the first one
+	// offered by the client is selected.
+	Subprotocols []string
+
+	// Compression enables permessage-deflate with clients offering it.
+	Compression bool
+
+	// MaxHeaderSize bounds the upgrade request, 8 KiB by default.
+	MaxHeaderSize int
+
+	// MaxMessageSize bounds a message once reassembled and decompressed,
+	// 16 MiB by default.
+	MaxMessageSize int64
+
+	// CloseTimeout is how long Close waits for the peer to answer, 5
+	// seconds by default.
+	CloseTimeout time.Duration
+}
+
+// Handler is told about the life of WebSocket connections. Its methods
+// are called from the read task of the connection, one at a time.
+type Handler interface {
+	OnOpen(c *Conn)
+
+	// OnMessage gets a whole message, data is only valid until it
+	// returns.
+	OnMessage(c *Conn, t MessageType, data []byte)
+
+	// OnClose gets the code and reason of the Close frame that ended the
+	// connection, CloseAbnormal if there was none.
+	OnClose(c *Conn, code int, reason string)
+}
+
+// PongHandler is implemented by handlers interested in pongs.
+type PongHandler interface {
+	OnPong(c *Conn, data []byte)
+}
+
+// Server upgrades connections and speaks WebSocket over them. It is a
+// tcp.Handler, start it with tcp.StartServer. It is a tcp.IdleHandler as
+// well: with a ReadIdleTimeout, idle peers are pinged, and closed if they
+// stay silent for another timeout.
+type Server struct {
+	conf    *Config
+	handler Handler
+	conns   sync.Map
+}
+
+// NewServer creates a server calling h.
+func NewServer(c *Config, h Handler) *Server {
+	return &Server{
+		conf:    c,
+		handler: h,
+	}
+}
+
+// OnAccept is the tcp.Handler implementation.
+func (s *Server) OnAccept() error {
+	return nil
+}
+
+// OnClose is the tcp.Handler implementation.
+func (s *Server) OnClose(conn net.Conn) {
+	v, ok := s.conns.LoadAndDelete(conn)
+	if !ok {
+		return
+	}
+
+	c := v.(*Conn)
+	c.stopCloseTimer()
+
+	if c.isOpen() {
+		code, reason := c.closeStatus()
+		s.handler.OnClose(c, code, reason)
+	}
+}
+
+// OnError is the tcp.Handler implementation, it closes the connection.
+func (s *Server) OnError(conn net.Conn) {
+	conn.Close()
+}
+
+// OnReadMessage is the tcp.Handler implementation.
+func (s *Server) OnReadMessage(conn net.Conn) error {
+	v, ok := s.conns.Load(conn)
+	if !ok {
+		v, _ = s.conns.LoadOrStore(conn, newConn(s, conn.(*tcp.Conn)))
+	}
+
+	err := v.(*Conn).read()
+	if err == errPeerClosed {
+		// The closing handshake is done, it's no read error.
+		conn.Close()
+		return nil
+	}
+
+	return err
+}
+
+// OnIdle is the tcp.IdleHandler implementation.
+func (s *Server) OnIdle(conn net.Conn, state tcp.IdleState) {
+	v, ok := s.conns.Load(conn)
+	if !ok || !v.(*Conn).isOpen() {
+		// Still no upgrade request.
+		if state != tcp.WriteIdle {
+			conn.Close()
+		}
+		return
+	}
+
+	c := v.(*Conn)
+
+	switch state {
+	case tcp.ReadIdle:
+		if !atomic.CompareAndSwapInt32(&c.pinged, 0, 1) {
+			conn.Close()
+			return
+		}
+
+		c.Ping(nil)
+	case tcp.WriteIdle:
+		c.Ping(nil)
+	case tcp.LifetimeExpired:
+		c.Close(CloseGoingAway, "")
+	}
+}
+
+func (s *Server) maxHeaderSize() int {
+	if s.conf.MaxHeaderSize > 0 {
+		return s.conf.MaxHeaderSize
+	}
+
+	return defaultMaxHeaderSize
+}
+
+func (s *Server) maxMessageSize() int64 {
+	if s.conf.MaxMessageSize > 0 {
+		return s.conf.MaxMessageSize
+	}
+
+	return defaultMaxMessageSize
+}
+
+func (s *Server) closeTimeout() time.Duration {
+	if s.conf.CloseTimeout > 0 {
+		return s.conf.CloseTimeout
+	}
+
+	return defaultCloseTimeout
+}
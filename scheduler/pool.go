@@ -86,6 +86,16 @@ func (p *Pool) Schedule(task Task) {
 	p.queue <- task
 }
 
+// TrySchedule push a task on queue if there is room, without waiting.
+func (p *Pool) TrySchedule(task Task) bool {
+	select {
+	case p.queue <- task:
+		return true
+	default:
+		return false
+	}
+}
+
 // ScheduleWithTimeout try to push a task on queue, if timeout, return false.
 func (p *Pool) ScheduleWithTimeout(timeout time.Duration, task Task) error {
 	timer := time.NewTimer(timeout)
@@ -31,6 +31,7 @@ package packet
 
 import (
 	"net"
+	"sync"
 )
 
 // Packet represents a UDP packet, including remote addr.
@@ -38,6 +39,70 @@ type Packet struct {
 	Payload []byte
 	Size    int
 	Remote  *net.UDPAddr
+
+	// The pool the packet goes back to on Release, nil if not pooled.
+	pool *Pool
+}
+
+// Pool recycles packets of the same capacity.
+type Pool struct {
+	size int
+	pool sync.Pool
+}
+
+// NewPool creates a pool of packets with a len([]byte) == size.
+func NewPool(size int) *Pool {
+	return &Pool{
+		size: size,
+	}
+}
+
+// Get returns a reset packet, the caller owns it until Release.
+func (p *Pool) Get() *Packet {
+	packet, ok := p.pool.Get().(*Packet)
+	if !ok {
+		packet = NewPacket(p.size)
+	}
+
+	packet.Reset()
+	packet.pool = p
+
+	return packet
+}
+
+// Put adds a packet allocated elsewhere to the pool, its payload must have
+// the pool's size.
+func (p *Pool) Put(packet *Packet) {
+	if len(packet.Payload) != p.size {
+		return
+	}
+
+	packet.pool = nil
+	p.pool.Put(packet)
+}
+
+// Release gives a pooled packet back, it must not be used afterwards.
+// Releasing a packet that wasn't pooled, or twice, does nothing.
+func (p *Packet) Release() {
+	pool := p.pool
+	if pool == nil {
+		return
+	}
+
+	pool.Put(p)
+}
+
+// Clone returns a copy of the packet that isn't pooled, for keeping a
+// packet after releasing it.
+func (p *Packet) Clone() *Packet {
+	payload := make([]byte, p.Size)
+	copy(payload, p.Payload[:p.Size])
+
+	return &Packet{
+		Payload: payload,
+		Size:    p.Size,
+		Remote:  p.Remote,
+	}
 }
 
 // NewPacket generates a Packet with a len([]byte) == cap.
@@ -0,0 +1,116 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package server
+
+import (
+	"net"
+
+	"github.com/fengyfei/nuts/udp/packet"
+	"golang.org/x/net/ipv4"
+)
+
+// batcher is implemented by both ipv4.PacketConn and ipv6.PacketConn.
+type batcher interface {
+	ReadBatch(ms []ipv4.Message, flags int) (int, error)
+	WriteBatch(ms []ipv4.Message, flags int) (int, error)
+}
+
+// batchConn reads and writes several packets per system call, using
+// recvmmsg and sendmmsg on Linux. Elsewhere, each call moves one packet.
+type batchConn struct {
+	conn   batcher
+	reads  []ipv4.Message
+	writes []ipv4.Message
+}
+
+func newBatchConn(s *socket, size int) *batchConn {
+	var b batcher = s.ip6
+	if s.ip4 != nil {
+		b = s.ip4
+	}
+
+	bc := &batchConn{
+		conn:   b,
+		reads:  make([]ipv4.Message, size),
+		writes: make([]ipv4.Message, size),
+	}
+
+	for i := range bc.reads {
+		bc.reads[i].Buffers = make([][]byte, 1)
+		bc.writes[i].Buffers = make([][]byte, 1)
+	}
+
+	return bc
+}
+
+// read fills packets, it returns how many were received.
+func (bc *batchConn) read(packets []*packet.Packet) (int, error) {
+	ms := bc.reads[:len(packets)]
+	for i, p := range packets {
+		ms[i].Buffers[0] = p.Payload
+	}
+
+	n, err := bc.conn.ReadBatch(ms, 0)
+
+	for i := 0; i < n; i++ {
+		packets[i].Size = ms[i].N
+		packets[i].Remote, _ = ms[i].Addr.(*net.UDPAddr)
+	}
+
+	for i := range ms {
+		ms[i].Buffers[0] = nil
+		ms[i].Addr = nil
+	}
+
+	return n, err
+}
+
+// write sends packets, it returns how many were written before an error.
+func (bc *batchConn) write(packets []*packet.Packet) (int, error) {
+	ms := bc.writes[:len(packets)]
+	for i, p := range packets {
+		ms[i].Buffers[0] = p.Payload[:p.Size]
+		if p.Remote != nil {
+			ms[i].Addr = p.Remote
+		}
+	}
+
+	n, err := bc.conn.WriteBatch(ms, 0)
+	if n < 0 {
+		n = 0
+	}
+
+	for i := range ms {
+		ms[i].Buffers[0] = nil
+		ms[i].Addr = nil
+	}
+
+	return n, err
+}
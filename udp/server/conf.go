@@ -29,10 +29,91 @@
 
 package server
 
+import (
+	"github.com/fengyfei/nuts/scheduler"
+)
+
+// Overflow tells what happens to a packet when dispatching it would wait.
+type Overflow uint8
+
+const (
+	// Block waits for room, leaving packets in the socket meanwhile.
+	Block Overflow = iota
+	// Drop drops the packet and counts it, see Stats.
+	Drop
+)
+
 // Conf represents the UDP server configuration, such as IP, port, etc.
+//
+// Address is a host name or an IPv4 or IPv6 literal, without brackets.
+// Empty, the server listens on every address; with network "udp", IPv4
+// and IPv6 on the same dual-stack socket.
 type Conf struct {
+	Network    string // "udp", "udp4" or "udp6", "udp" by default
 	Address    string // Local Address
 	Port       string // Local Port
 	PacketSize int    // Packet max size
-	CacheCount int    // Cache size
+	CacheCount int    // Packets allocated up front, spread among the sockets
+	BatchSize  int    // Packets per system call, 1 by default
+	Sockets    int    // Sockets bound with SO_REUSEPORT, 1 by default
+
+	// Socket options, see Server.SocketOptions for the values in effect.
+	// Buffers over the system maximum are forced when the process is
+	// allowed to, with SO_RCVBUFFORCE and SO_SNDBUFFORCE on Linux.
+	ReadBuffer  int  // SO_RCVBUF, 64 KiB by default
+	WriteBuffer int  // SO_SNDBUF, 64 KiB by default
+	TOS         int  // IP_TOS and IPV6_TCLASS, the DSCP is TOS >> 2
+	TTL         int  // IP_TTL and IPV6_UNICAST_HOPS, system default when 0
+	NoBroadcast bool // Clears SO_BROADCAST, which Go sets on UDP sockets
+
+	// Multicast, see Server.JoinGroup for changes at runtime.
+	Groups              []Group // Groups joined at start
+	MulticastInterface  string  // Interface multicast packets leave on
+	MulticastHops       int     // IP_MULTICAST_TTL and IPV6_MULTICAST_HOPS
+	NoMulticastLoopback bool    // Don't deliver packets sent to local members
+
+	// Pool runs OnPacket when not nil, instead of the receiving goroutine.
+	// Ordered keeps the packets of each remote address in order, at most
+	// PeerBacklog of them wait for their turn, 128 by default.
+	Pool        *scheduler.Pool
+	Ordered     bool
+	PeerBacklog int
+	Overflow    Overflow
+}
+
+const (
+	defaultPeerBacklog = 128
+	defaultBufferSize  = 64 << 10
+)
+
+func (conf *Conf) network() string {
+	if conf.Network == "" {
+		return "udp"
+	}
+
+	return conf.Network
+}
+
+func (conf *Conf) readBuffer() int {
+	if conf.ReadBuffer > 0 {
+		return conf.ReadBuffer
+	}
+
+	return defaultBufferSize
+}
+
+func (conf *Conf) writeBuffer() int {
+	if conf.WriteBuffer > 0 {
+		return conf.WriteBuffer
+	}
+
+	return defaultBufferSize
+}
+
+func (conf *Conf) peerBacklog() int {
+	if conf.PeerBacklog > 0 {
+		return conf.PeerBacklog
+	}
+
+	return defaultPeerBacklog
 }
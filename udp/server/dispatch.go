@@ -0,0 +1,182 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package server
+
+import (
+	"net/netip"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fengyfei/nuts/scheduler"
+	"github.com/fengyfei/nuts/udp/packet"
+)
+
+// Stats are the packet counters of a server.
+type Stats struct {
+	Received   uint64 // Packets read from the sockets
+	Dispatched uint64 // Packets handed to Conf.Pool
+	Dropped    uint64 // Packets dropped on overflow
+
+	Sockets []SocketStats // In the order sockets were bound
+}
+
+// dispatcher runs OnPacket on a scheduler.Pool.
+type dispatcher struct {
+	// Counters, accessed atomically.
+	dispatched uint64
+	dropped    uint64
+
+	pool     *scheduler.Pool
+	handler  Handler
+	overflow Overflow
+	ordered  bool
+	backlog  int
+
+	// Packets waiting behind the one being handled, per remote address.
+	// A peer is present while a task handles its packets.
+	mu    sync.Mutex
+	room  *sync.Cond
+	peers map[netip.AddrPort][]*packet.Packet
+
+	// Packets dispatched and not handled yet.
+	wg sync.WaitGroup
+}
+
+func newDispatcher(conf *Conf, handler Handler) *dispatcher {
+	d := &dispatcher{
+		pool:     conf.Pool,
+		handler:  handler,
+		overflow: conf.Overflow,
+		ordered:  conf.Ordered,
+		backlog:  conf.peerBacklog(),
+		peers:    make(map[netip.AddrPort][]*packet.Packet),
+	}
+	d.room = sync.NewCond(&d.mu)
+
+	return d
+}
+
+// dispatch hands p over to the pool, p is released when dropped.
+func (d *dispatcher) dispatch(p *packet.Packet) {
+	if !d.ordered {
+		d.wg.Add(1)
+		if !d.schedule(scheduler.TaskFunc(func() error {
+			defer d.wg.Done()
+			return d.handler.OnPacket(p)
+		})) {
+			d.wg.Done()
+			d.drop(p)
+		}
+		return
+	}
+
+	key := p.Remote.AddrPort()
+
+	d.mu.Lock()
+	if queue, busy := d.peers[key]; busy {
+		for len(queue) >= d.backlog && d.overflow == Block {
+			d.room.Wait()
+			queue, busy = d.peers[key]
+		}
+
+		// The task may have finished while waiting.
+		if busy {
+			if len(queue) >= d.backlog {
+				d.mu.Unlock()
+				d.drop(p)
+				return
+			}
+
+			d.peers[key] = append(queue, p)
+			d.mu.Unlock()
+			atomic.AddUint64(&d.dispatched, 1)
+			return
+		}
+	}
+	d.peers[key] = nil
+	d.mu.Unlock()
+
+	d.wg.Add(1)
+	if !d.schedule(scheduler.TaskFunc(func() error {
+		defer d.wg.Done()
+		return d.drain(key, p)
+	})) {
+		d.wg.Done()
+
+		d.mu.Lock()
+		delete(d.peers, key)
+		d.mu.Unlock()
+
+		d.drop(p)
+	}
+}
+
+// drain handles p, then the packets queued behind it.
+func (d *dispatcher) drain(key netip.AddrPort, p *packet.Packet) error {
+	for {
+		d.handler.OnPacket(p)
+
+		d.mu.Lock()
+		queue := d.peers[key]
+		if len(queue) == 0 {
+			delete(d.peers, key)
+			d.room.Broadcast()
+			d.mu.Unlock()
+			return nil
+		}
+
+		p = queue[0]
+		queue[0] = nil
+		d.peers[key] = queue[1:]
+		d.room.Broadcast()
+		d.mu.Unlock()
+	}
+}
+
+func (d *dispatcher) schedule(task scheduler.Task) bool {
+	if d.overflow == Block {
+		d.pool.Schedule(task)
+	} else if !d.pool.TrySchedule(task) {
+		return false
+	}
+
+	atomic.AddUint64(&d.dispatched, 1)
+	return true
+}
+
+func (d *dispatcher) drop(p *packet.Packet) {
+	atomic.AddUint64(&d.dropped, 1)
+	p.Release()
+}
+
+// wait returns once every packet dispatched was handled.
+func (d *dispatcher) wait() {
+	d.wg.Wait()
+}
@@ -34,6 +34,10 @@ import (
 )
 
 // Handler represents the operation dispatched by the UDP server.
+//
+// OnPacket owns the packet it's given: it may keep it or hand it to another
+// goroutine, and calls Release once done so that the server reuses it.
+// A packet never released is left to the garbage collector.
 type Handler interface {
 	OnPacket(*packet.Packet) error
 	OnError(error) error
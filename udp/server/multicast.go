@@ -0,0 +1,301 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package server
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	"golang.org/x/net/ipv4"
+)
+
+var (
+	// ErrNotMulticast means an address isn't a multicast group.
+	ErrNotMulticast = errors.New("not a multicast address")
+
+	// ErrFamily means an IPv6 group was given to an IPv4 socket.
+	ErrFamily = errors.New("address family not supported by the socket")
+
+	// ErrNotJoined means the group wasn't joined on the interface.
+	ErrNotJoined = errors.New("multicast group not joined")
+)
+
+// Group is a multicast group joined on an interface. A nil Interface lets
+// the system pick one.
+type Group struct {
+	IP        net.IP
+	Interface *net.Interface
+}
+
+func (g Group) equal(o Group) bool {
+	return g.IP.Equal(o.IP) && g.index() == o.index()
+}
+
+func (g Group) index() int {
+	if g.Interface == nil {
+		return 0
+	}
+
+	return g.Interface.Index
+}
+
+// groups are the multicast groups joined by a server.
+type groups struct {
+	mu     sync.Mutex
+	joined []Group
+}
+
+// JoinGroup joins g, a group's packets are then received like any other.
+// Sending to a group only takes Send. With several sockets, the group is
+// joined on the first one only and the others are kept out of it, since
+// the system delivers a group's packets to every socket bound to the port.
+// That needs Linux, elsewhere it fails with errors.ErrUnsupported.
+func (server *Server) JoinGroup(g Group) error {
+	if !g.IP.IsMulticast() {
+		return ErrNotMulticast
+	}
+
+	server.groups.mu.Lock()
+	defer server.groups.mu.Unlock()
+
+	for _, s := range server.sockets[1:] {
+		if err := s.setMulticastAll(false); err != nil {
+			return err
+		}
+	}
+
+	if err := server.sockets[0].joinGroup(g); err != nil {
+		return err
+	}
+
+	server.groups.joined = append(server.groups.joined, g)
+
+	return nil
+}
+
+// LeaveGroup leaves a group joined with JoinGroup.
+func (server *Server) LeaveGroup(g Group) error {
+	server.groups.mu.Lock()
+	defer server.groups.mu.Unlock()
+
+	for i, joined := range server.groups.joined {
+		if !joined.equal(g) {
+			continue
+		}
+
+		// Still joined if leaving fails.
+		if err := server.sockets[0].leaveGroup(joined); err != nil {
+			return err
+		}
+
+		server.groups.joined = append(server.groups.joined[:i], server.groups.joined[i+1:]...)
+
+		return nil
+	}
+
+	return ErrNotJoined
+}
+
+// Groups returns the multicast groups joined.
+func (server *Server) Groups() []Group {
+	server.groups.mu.Lock()
+	defer server.groups.mu.Unlock()
+
+	return append([]Group(nil), server.groups.joined...)
+}
+
+// SetMulticastInterface sets the interface multicast packets are sent on,
+// nil lets the system pick one.
+func (server *Server) SetMulticastInterface(ifi *net.Interface) error {
+	return server.each(func(s *socket) error {
+		return s.setMulticastInterface(ifi)
+	})
+}
+
+// SetMulticastHops sets how many hops multicast packets sent may go, 1
+// keeps them on the local network.
+func (server *Server) SetMulticastHops(hops int) error {
+	return server.each(func(s *socket) error {
+		return s.setMulticastHops(hops)
+	})
+}
+
+// SetMulticastLoopback tells whether multicast packets sent are delivered
+// to the local host as well.
+func (server *Server) SetMulticastLoopback(on bool) error {
+	return server.each(func(s *socket) error {
+		return s.setMulticastLoopback(on)
+	})
+}
+
+// configureMulticast applies the multicast options of the configuration
+// to s.
+func (server *Server) configureMulticast(s *socket) error {
+	conf := server.conf
+
+	if conf.MulticastInterface != "" {
+		ifi, err := net.InterfaceByName(conf.MulticastInterface)
+		if err != nil {
+			return err
+		}
+
+		if err = s.setMulticastInterface(ifi); err != nil {
+			return err
+		}
+	}
+
+	if conf.MulticastHops != 0 {
+		if err := s.setMulticastHops(conf.MulticastHops); err != nil {
+			return err
+		}
+	}
+
+	if conf.NoMulticastLoopback {
+		return s.setMulticastLoopback(false)
+	}
+
+	return nil
+}
+
+func (server *Server) each(fn func(s *socket) error) error {
+	for _, s := range server.sockets {
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ipv4 returns IPv4 access to s, dual-stack sockets included.
+func (s *socket) ipv4() *ipv4.PacketConn {
+	if s.ip4 != nil {
+		return s.ip4
+	}
+
+	return ipv4.NewPacketConn(s.conn)
+}
+
+func (s *socket) joinGroup(g Group) error {
+	addr := &net.UDPAddr{IP: g.IP}
+
+	if g.IP.To4() != nil {
+		return s.ipv4().JoinGroup(g.Interface, addr)
+	}
+
+	if s.ip6 == nil {
+		return ErrFamily
+	}
+
+	return s.ip6.JoinGroup(g.Interface, addr)
+}
+
+func (s *socket) leaveGroup(g Group) error {
+	addr := &net.UDPAddr{IP: g.IP}
+
+	if g.IP.To4() != nil {
+		return s.ipv4().LeaveGroup(g.Interface, addr)
+	}
+
+	if s.ip6 == nil {
+		return ErrFamily
+	}
+
+	return s.ip6.LeaveGroup(g.Interface, addr)
+}
+
+// The setters below cover both families of a dual-stack socket.
+
+// setMulticastAll tells whether s receives the packets of groups joined by
+// other sockets bound to the same port, which the system does by default.
+func (s *socket) setMulticastAll(on bool) error {
+	if s.ip4 != nil {
+		return setMulticastAll(s.conn, false, on)
+	}
+
+	if err := setMulticastAll(s.conn, true, on); err != nil {
+		return err
+	}
+
+	if s.dualStack() {
+		return setMulticastAll(s.conn, false, on)
+	}
+
+	return nil
+}
+
+func (s *socket) setMulticastInterface(ifi *net.Interface) error {
+	if s.ip4 != nil {
+		return s.ip4.SetMulticastInterface(ifi)
+	}
+
+	if err := s.ip6.SetMulticastInterface(ifi); err != nil {
+		return err
+	}
+
+	if s.dualStack() {
+		return s.ipv4().SetMulticastInterface(ifi)
+	}
+
+	return nil
+}
+
+func (s *socket) setMulticastHops(hops int) error {
+	if s.ip4 != nil {
+		return s.ip4.SetMulticastTTL(hops)
+	}
+
+	if err := s.ip6.SetMulticastHopLimit(hops); err != nil {
+		return err
+	}
+
+	if s.dualStack() {
+		return s.ipv4().SetMulticastTTL(hops)
+	}
+
+	return nil
+}
+
+func (s *socket) setMulticastLoopback(on bool) error {
+	if s.ip4 != nil {
+		return s.ip4.SetMulticastLoopback(on)
+	}
+
+	if err := s.ip6.SetMulticastLoopback(on); err != nil {
+		return err
+	}
+
+	if s.dualStack() {
+		return s.ipv4().SetMulticastLoopback(on)
+	}
+
+	return nil
+}
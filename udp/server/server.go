@@ -30,118 +30,384 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"net"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/fengyfei/nuts/udp/packet"
 )
 
-const (
-	readBufferDefaultSize  = 65536
-	writeBufferDefaultSize = 65536
+var (
+	// ErrServerClosed means the server was shut down.
+	ErrServerClosed = errors.New("server closed")
+
+	// ErrReusePort means SO_REUSEPORT isn't available for Conf.Sockets.
+	ErrReusePort = errors.New("SO_REUSEPORT not supported")
 )
 
 // Server is a generic UDP server.
 type Server struct {
-	conf     *Conf
-	conn     *net.UDPConn
-	handler  Handler
-	buffer   []*packet.Packet
-	sender   chan *packet.Packet
-	shutdown chan struct{}
+	conf    *Conf
+	sockets []*socket
+	handler Handler
+	sender  chan *packet.Packet
+
+	// Runs OnPacket on Conf.Pool, nil without one.
+	dispatcher *dispatcher
+
+	// Multicast groups joined.
+	groups groups
+
+	// Guards closing sender, Send holds it for reading.
+	mu     sync.RWMutex
+	closed bool
+
+	quit      chan struct{}
+	finished  chan struct{}
+	once      sync.Once
+	receivers sync.WaitGroup
+	senders   sync.WaitGroup
 }
 
 // NewServer creates a UDP server instance.
 func NewServer(conf *Conf, handler Handler) (*Server, error) {
-	var (
-		conn *net.UDPConn
-	)
+	server := &Server{
+		conf:     conf,
+		handler:  handler,
+		sender:   make(chan *packet.Packet, 64),
+		quit:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
 
-	addr, err := net.ResolveUDPAddr("udp", conf.Address+":"+conf.Port)
-	if err != nil {
-		return nil, err
+	n := conf.Sockets
+	if n < 1 {
+		n = 1
 	}
 
-	conn, err = net.ListenUDP("udp", addr)
-	if err != nil {
-		return nil, err
+	address := net.JoinHostPort(conf.Address, conf.Port)
+	for i := 0; i < n; i++ {
+		conn, err := listen(conf.network(), address, n > 1)
+		if err != nil {
+			server.closeSockets()
+			return nil, err
+		}
+
+		// The others must bind the very port the first one got.
+		address = conn.LocalAddr().String()
+
+		s := newSocket(conn, conf.PacketSize)
+		server.sockets = append(server.sockets, s)
+
+		if err = server.configure(s); err != nil {
+			server.closeSockets()
+			return nil, err
+		}
+
+		if err = server.configureMulticast(s); err != nil {
+			server.closeSockets()
+			return nil, err
+		}
+
+		if conf.BatchSize > 1 {
+			s.batch = newBatchConn(s, conf.BatchSize)
+		}
 	}
 
-	server := &Server{
-		conf:     conf,
-		conn:     conn,
-		handler:  handler,
-		buffer:   make([]*packet.Packet, conf.CacheCount),
-		sender:   make(chan *packet.Packet, 64),
-		shutdown: make(chan struct{}),
+	for _, g := range conf.Groups {
+		if err := server.JoinGroup(g); err != nil {
+			server.closeSockets()
+			return nil, err
+		}
 	}
+
 	server.prepare()
 
-	go server.start()
+	if conf.Pool != nil {
+		server.dispatcher = newDispatcher(conf, handler)
+	}
+
+	// Sockets share the send queue, spreading the packets among them.
+	for _, s := range server.sockets {
+		server.receivers.Add(1)
+		go server.receive(s)
+
+		server.senders.Add(1)
+		go server.send(s)
+	}
 
 	return server, nil
 }
 
+// prepare spreads Conf.CacheCount packets among the pools of the sockets.
 func (server *Server) prepare() {
-	server.conn.SetReadBuffer(readBufferDefaultSize)
-	server.conn.SetWriteBuffer(writeBufferDefaultSize)
+	n := len(server.sockets)
 
 	for i := 0; i < server.conf.CacheCount; i++ {
-		server.buffer[i] = packet.NewPacket(server.conf.PacketSize)
+		server.sockets[i%n].packets.Put(packet.NewPacket(server.conf.PacketSize))
+	}
+}
+
+// Addr returns the address the server is bound to.
+func (server *Server) Addr() net.Addr {
+	return server.sockets[0].conn.LocalAddr()
+}
+
+// Stats returns the packet counters of the server, and of each socket.
+func (server *Server) Stats() Stats {
+	var stats Stats
+
+	for _, s := range server.sockets {
+		ss := s.stats()
+		stats.Received += ss.Received
+		stats.Sockets = append(stats.Sockets, ss)
+	}
+
+	if d := server.dispatcher; d != nil {
+		stats.Dispatched = atomic.LoadUint64(&d.dispatched)
+		stats.Dropped = atomic.LoadUint64(&d.dropped)
 	}
+
+	return stats
 }
 
-// Shutdown the UDP server.
-func (server *Server) Shutdown() {
-	server.shutdown <- struct{}{}
+// Shutdown stops receiving, waits for the packets dispatched to be
+// handled, sends the packets queued, then closes the socket and calls
+// OnClose. It waits until every goroutine of the server returned, or ctx
+// is done. Then the sockets are closed, dropping the packets not sent yet,
+// and ctx.Err() is returned at once; OnClose is still called once the
+// handlers running return. Send fails with ErrServerClosed afterwards.
+func (server *Server) Shutdown(ctx context.Context) error {
+	server.once.Do(server.stop)
+
+	select {
+	case <-server.finished:
+		return nil
+	case <-ctx.Done():
+		// Writing fails on a closed socket, so the senders are done quickly.
+		server.closeSockets()
+		return ctx.Err()
+	}
 }
 
-// Start the messaging loop.
-func (server *Server) start() {
-	go server.receive()
+func (server *Server) stop() {
+	close(server.quit)
+
+	// Wake up the receivers, sockets stay open for the senders.
+	for _, s := range server.sockets {
+		s.conn.SetReadDeadline(time.Now())
+	}
+
+	go func() {
+		server.receivers.Wait()
+
+		// Handlers may still send replies.
+		if server.dispatcher != nil {
+			server.dispatcher.wait()
+		}
+
+		// Sends still running get their packet queued.
+		server.mu.Lock()
+		server.closed = true
+		close(server.sender)
+		server.mu.Unlock()
+
+		server.senders.Wait()
+		server.closeSockets()
+		server.handler.OnClose()
+		close(server.finished)
+	}()
+}
+
+func (server *Server) closeSockets() {
+	for _, s := range server.sockets {
+		s.conn.Close()
+	}
+}
+
+// send writes the queued packets on s until the queue is closed and empty.
+func (server *Server) send(s *socket) {
+	defer server.senders.Done()
+
+	if s.batch != nil {
+		server.sendBatches(s)
+		return
+	}
+
+	for packet := range server.sender {
+		err := packet.Write(s.conn)
+		packet.Release()
+
+		if err != nil {
+			server.failed(s, err)
+		} else {
+			atomic.AddUint64(&s.sent, 1)
+		}
+	}
+}
+
+// failed counts err and reports it, unless the socket was closed.
+func (server *Server) failed(s *socket, err error) {
+	if errors.Is(err, net.ErrClosed) {
+		return
+	}
+
+	atomic.AddUint64(&s.errors, 1)
+	server.handler.OnError(err)
+}
+
+func (server *Server) receive(s *socket) {
+	defer server.receivers.Done()
+
+	if s.batch != nil {
+		server.receiveBatches(s)
+		return
+	}
 
 	for {
+		// The handler owns every packet it's given.
+		packet := s.packets.Get()
+
+		err := packet.Read(s.conn)
+
 		select {
-		case <-server.shutdown:
-			server.conn.Close()
+		case <-server.quit:
+			packet.Release()
 			return
+		default:
+		}
 
-		case packet := <-server.sender:
-			err := packet.Write(server.conn)
+		if err != nil {
+			packet.Release()
 
-			if err != nil {
-				server.handler.OnError(err)
+			// The socket was closed under us.
+			if errors.Is(err, net.ErrClosed) {
+				return
 			}
+
+			server.failed(s, err)
+			continue
 		}
+
+		atomic.AddUint64(&s.received, 1)
+		server.handle(packet)
 	}
 }
 
-func (server *Server) receive() {
-	index := 0
-	cap := server.conf.CacheCount
+// receiveBatches is receive, reading Conf.BatchSize packets at most per
+// system call.
+func (server *Server) receiveBatches(s *socket) {
+	packets := make([]*packet.Packet, server.conf.BatchSize)
+	for i := range packets {
+		packets[i] = s.packets.Get()
+	}
+
+	defer func() {
+		for _, p := range packets {
+			p.Release()
+		}
+	}()
 
 	for {
-		// Pick a packet and reset it for receiving.
-		packet := server.buffer[index]
-		packet.Reset()
+		n, err := s.batch.read(packets)
+
+		select {
+		case <-server.quit:
+			return
+		default:
+		}
+
+		atomic.AddUint64(&s.received, uint64(n))
 
-		err := packet.Read(server.conn)
+		// The handler owns every packet it's given, they are replaced.
+		for i := 0; i < n; i++ {
+			server.handle(packets[i])
+			packets[i] = s.packets.Get()
+		}
 
 		if err != nil {
-			server.handler.OnError(err)
-		} else {
-			server.handler.OnPacket(packet)
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			server.failed(s, err)
 		}
+	}
+}
 
-		index = (index + 1) % cap
+func (server *Server) handle(packet *packet.Packet) {
+	if server.dispatcher != nil {
+		server.dispatcher.dispatch(packet)
+	} else {
+		server.handler.OnPacket(packet)
 	}
 }
 
-// Send a message.
+// sendBatches is send, writing Conf.BatchSize packets at most per system
+// call.
+func (server *Server) sendBatches(s *socket) {
+	batch := make([]*packet.Packet, 0, server.conf.BatchSize)
+
+	for packet := range server.sender {
+		batch = append(batch[:0], packet)
+
+		// Take what's already queued, without waiting.
+	fill:
+		for len(batch) < cap(batch) {
+			select {
+			case packet, ok := <-server.sender:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, packet)
+			default:
+				break fill
+			}
+		}
+
+		for pending := batch; len(pending) > 0; {
+			n, err := s.batch.write(pending)
+			atomic.AddUint64(&s.sent, uint64(n))
+
+			if err != nil {
+				server.failed(s, err)
+			}
+
+			// Skip the packet that failed.
+			if n < len(pending) && (err != nil || n == 0) {
+				n++
+			}
+
+			for i, p := range pending[:n] {
+				p.Release()
+				pending[i] = nil
+			}
+			pending = pending[n:]
+		}
+	}
+}
+
+// Send a message. It fails with ErrServerClosed once the server is shut
+// down.
 func (server *Server) Send(payload []byte, remote *net.UDPAddr) error {
-	packet := &packet.Packet{
+	return server.SendPacket(&packet.Packet{
 		Payload: payload,
 		Size:    len(payload),
 		Remote:  remote,
+	})
+}
+
+// SendPacket queues p to p.Remote, the server releases it once written.
+// A received packet can be sent back without copying.
+func (server *Server) SendPacket(packet *packet.Packet) error {
+	server.mu.RLock()
+	defer server.mu.RUnlock()
+
+	if server.closed {
+		packet.Release()
+		return ErrServerClosed
 	}
 
 	server.sender <- packet
@@ -0,0 +1,121 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package server
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/fengyfei/nuts/udp/packet"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// SocketStats are the counters of one socket of a server.
+type SocketStats struct {
+	Received uint64 // Packets read
+	Sent     uint64 // Packets written
+	Errors   uint64 // Failed reads and writes
+}
+
+// socket is one of the sockets of a server, with its own receive and send
+// goroutines.
+type socket struct {
+	// Counters, accessed atomically.
+	received uint64
+	sent     uint64
+	errors   uint64
+
+	conn *net.UDPConn
+
+	// IP level access to conn, ip4 is set for IPv4 sockets and ip6 for
+	// IPv6 ones, dual-stack included.
+	ip4 *ipv4.PacketConn
+	ip6 *ipv6.PacketConn
+
+	// Set when Conf.BatchSize is over 1.
+	batch *batchConn
+
+	// Packets received on this socket, they come back here on Release.
+	packets *packet.Pool
+}
+
+func newSocket(conn *net.UDPConn, size int) *socket {
+	s := &socket{
+		conn:    conn,
+		packets: packet.NewPool(size),
+	}
+
+	// A wildcard listener of network "udp" is dual-stack.
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok && addr.IP.To4() != nil {
+		s.ip4 = ipv4.NewPacketConn(conn)
+	} else {
+		s.ip6 = ipv6.NewPacketConn(conn)
+	}
+
+	return s
+}
+
+func (s *socket) stats() SocketStats {
+	return SocketStats{
+		Received: atomic.LoadUint64(&s.received),
+		Sent:     atomic.LoadUint64(&s.sent),
+		Errors:   atomic.LoadUint64(&s.errors),
+	}
+}
+
+// listen binds a socket, with SO_REUSEPORT when asked so that the kernel
+// balances datagrams among the sockets bound to the same address.
+func listen(network, address string, reusePort bool) (*net.UDPConn, error) {
+	lc := net.ListenConfig{}
+
+	if reusePort {
+		lc.Control = func(_, _ string, rc syscall.RawConn) error {
+			var err error
+
+			if cerr := rc.Control(func(fd uintptr) {
+				err = setReusePort(fd)
+			}); cerr != nil {
+				return cerr
+			}
+
+			return err
+		}
+	}
+
+	conn, err := lc.ListenPacket(context.Background(), network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return conn.(*net.UDPConn), nil
+}
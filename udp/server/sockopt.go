@@ -0,0 +1,171 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package server
+
+import (
+	"net"
+
+	"golang.org/x/net/ipv4"
+)
+
+// SocketOptions are the options in effect on a socket, as reported by the
+// kernel. Linux reports buffers twice the size asked, to account for its
+// bookkeeping.
+type SocketOptions struct {
+	ReadBuffer  int
+	WriteBuffer int
+	TOS         int
+	TTL         int
+	Broadcast   bool
+}
+
+// configure applies the socket options of the configuration to s.
+func (server *Server) configure(s *socket) error {
+	conf := server.conf
+
+	if err := s.conn.SetReadBuffer(conf.readBuffer()); err != nil {
+		return err
+	}
+
+	if err := s.conn.SetWriteBuffer(conf.writeBuffer()); err != nil {
+		return err
+	}
+
+	if err := forceBuffers(s.conn, conf.ReadBuffer, conf.WriteBuffer); err != nil {
+		return err
+	}
+
+	if conf.TOS != 0 {
+		if err := s.setTOS(conf.TOS); err != nil {
+			return err
+		}
+	}
+
+	if conf.TTL != 0 {
+		if err := s.setTTL(conf.TTL); err != nil {
+			return err
+		}
+	}
+
+	if conf.NoBroadcast {
+		return setBroadcast(s.conn, false)
+	}
+
+	return nil
+}
+
+// setTOS sets the IPv6 traffic class, and the IPv4 TOS of a dual-stack
+// socket as well.
+func (s *socket) setTOS(tos int) error {
+	if s.ip4 != nil {
+		return s.ip4.SetTOS(tos)
+	}
+
+	if err := s.ip6.SetTrafficClass(tos); err != nil {
+		return err
+	}
+
+	if s.dualStack() {
+		return ipv4.NewConn(s.conn).SetTOS(tos)
+	}
+
+	return nil
+}
+
+func (s *socket) setTTL(ttl int) error {
+	if s.ip4 != nil {
+		return s.ip4.SetTTL(ttl)
+	}
+
+	if err := s.ip6.SetHopLimit(ttl); err != nil {
+		return err
+	}
+
+	if s.dualStack() {
+		return ipv4.NewConn(s.conn).SetTTL(ttl)
+	}
+
+	return nil
+}
+
+// dualStack tells whether an IPv6 socket also carries IPv4 traffic.
+func (s *socket) dualStack() bool {
+	addr, ok := s.conn.LocalAddr().(*net.UDPAddr)
+	return ok && s.ip6 != nil && addr.IP.IsUnspecified() && !v6Only(s.conn)
+}
+
+func (s *socket) options() (SocketOptions, error) {
+	var (
+		opts SocketOptions
+		err  error
+	)
+
+	if opts.ReadBuffer, opts.WriteBuffer, err = buffers(s.conn); err != nil {
+		return opts, err
+	}
+
+	if s.ip4 != nil {
+		if opts.TOS, err = s.ip4.TOS(); err != nil {
+			return opts, err
+		}
+
+		if opts.TTL, err = s.ip4.TTL(); err != nil {
+			return opts, err
+		}
+	} else {
+		if opts.TOS, err = s.ip6.TrafficClass(); err != nil {
+			return opts, err
+		}
+
+		if opts.TTL, err = s.ip6.HopLimit(); err != nil {
+			return opts, err
+		}
+	}
+
+	opts.Broadcast, err = broadcast(s.conn)
+
+	return opts, err
+}
+
+// SocketOptions returns the options in effect on each socket, in the
+// order they were bound.
+func (server *Server) SocketOptions() ([]SocketOptions, error) {
+	opts := make([]SocketOptions, len(server.sockets))
+
+	for i, s := range server.sockets {
+		var err error
+
+		if opts[i], err = s.options(); err != nil {
+			return nil, err
+		}
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,74 @@
+//go:build linux
+
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package server
+
+import (
+	"errors"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// forceBuffers sets the buffers asked over the system maximum with
+// SO_RCVBUFFORCE and SO_SNDBUFFORCE, which need CAP_NET_ADMIN. Without
+// it, the buffers stay at the maximum.
+func forceBuffers(conn *net.UDPConn, read, write int) error {
+	for _, b := range []struct {
+		size, name, force int
+	}{
+		{read, unix.SO_RCVBUF, unix.SO_RCVBUFFORCE},
+		{write, unix.SO_SNDBUF, unix.SO_SNDBUFFORCE},
+	} {
+		if b.size <= 0 {
+			continue
+		}
+
+		// The kernel doubles the size asked.
+		v, err := getsockopt(conn, unix.SOL_SOCKET, b.name)
+		if err != nil {
+			return err
+		}
+
+		if v/2 >= b.size {
+			continue
+		}
+
+		err = control(conn, func(fd int) error {
+			return unix.SetsockoptInt(fd, unix.SOL_SOCKET, b.force, b.size)
+		})
+		if err != nil && !errors.Is(err, unix.EPERM) {
+			return err
+		}
+	}
+
+	return nil
+}
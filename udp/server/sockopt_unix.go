@@ -0,0 +1,102 @@
+//go:build unix
+
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package server
+
+import (
+	"net"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func setReusePort(fd uintptr) error {
+	return os.NewSyscallError("setsockopt", unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1))
+}
+
+func setBroadcast(conn *net.UDPConn, on bool) error {
+	v := 0
+	if on {
+		v = 1
+	}
+
+	return control(conn, func(fd int) error {
+		return unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_BROADCAST, v)
+	})
+}
+
+func broadcast(conn *net.UDPConn) (bool, error) {
+	v, err := getsockopt(conn, unix.SOL_SOCKET, unix.SO_BROADCAST)
+	return v != 0, err
+}
+
+func buffers(conn *net.UDPConn) (read, write int, err error) {
+	if read, err = getsockopt(conn, unix.SOL_SOCKET, unix.SO_RCVBUF); err != nil {
+		return 0, 0, err
+	}
+
+	write, err = getsockopt(conn, unix.SOL_SOCKET, unix.SO_SNDBUF)
+	return read, write, err
+}
+
+func v6Only(conn *net.UDPConn) bool {
+	v, err := getsockopt(conn, unix.IPPROTO_IPV6, unix.IPV6_V6ONLY)
+	return err == nil && v != 0
+}
+
+func getsockopt(conn *net.UDPConn, level, name int) (int, error) {
+	var v int
+
+	err := control(conn, func(fd int) error {
+		var err error
+		v, err = unix.GetsockoptInt(fd, level, name)
+		return err
+	})
+
+	return v, err
+}
+
+// control runs fn on the descriptor of conn.
+func control(conn *net.UDPConn, fn func(fd int) error) error {
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var ferr error
+	if err = rc.Control(func(fd uintptr) {
+		ferr = fn(int(fd))
+	}); err != nil {
+		return err
+	}
+
+	return os.NewSyscallError("sockopt", ferr)
+}
@@ -0,0 +1,647 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co., Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package test
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fengyfei/nuts/scheduler"
+	"github.com/fengyfei/nuts/udp/packet"
+	"github.com/fengyfei/nuts/udp/server"
+)
+
+// echo sends every packet back, closed counts the calls to OnClose.
+type echo struct {
+	server *server.Server
+	ready  chan struct{}
+	closed int32
+}
+
+func (h *echo) OnPacket(p *packet.Packet) error {
+	<-h.ready
+
+	resp := make([]byte, p.Size)
+	copy(resp, p.Payload[:p.Size])
+
+	return h.server.Send(resp, p.Remote)
+}
+
+func (h *echo) OnError(err error) error {
+	return nil
+}
+
+func (h *echo) OnClose() error {
+	atomic.AddInt32(&h.closed, 1)
+	return nil
+}
+
+func start(t *testing.T, conf *server.Conf) (*server.Server, *echo) {
+	h := &echo{
+		ready: make(chan struct{}),
+	}
+
+	s, err := server.NewServer(conf, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.server = s
+	close(h.ready)
+
+	return s, h
+}
+
+func dial(t *testing.T, s *server.Server) *net.UDPConn {
+	conn, err := net.DialUDP("udp", nil, s.Addr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func TestShutdown(t *testing.T) {
+	s, h := start(t, &server.Conf{
+		Address:    "127.0.0.1",
+		Port:       "0",
+		PacketSize: 64,
+		CacheCount: 4,
+	})
+
+	conn := dial(t, s)
+	defer conn.Close()
+
+	b := make([]byte, 64)
+
+	conn.Write([]byte("ping"))
+	if n, err := conn.Read(b); err != nil || string(b[:n]) != "ping" {
+		t.Fatalf("read %q, %v", b[:n], err)
+	}
+
+	// Packets queued before shutting down are still sent.
+	remote := conn.LocalAddr().(*net.UDPAddr)
+	for i := 0; i < 32; i++ {
+		if err := s.Send([]byte("bye"), remote); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 32; i++ {
+		if n, err := conn.Read(b); err != nil || string(b[:n]) != "bye" {
+			t.Fatalf("packet %d: read %q, %v", i, b[:n], err)
+		}
+	}
+
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Send([]byte("late"), remote); err != server.ErrServerClosed {
+		t.Fatalf("send after shutdown: %v", err)
+	}
+
+	if n := atomic.LoadInt32(&h.closed); n != 1 {
+		t.Fatalf("OnClose called %d times", n)
+	}
+}
+
+// keeper holds on to every packet without releasing it.
+type keeper struct {
+	echo
+	kept chan *packet.Packet
+}
+
+func (h *keeper) OnPacket(p *packet.Packet) error {
+	h.kept <- p
+	return nil
+}
+
+func TestPacketOwnership(t *testing.T) {
+	h := &keeper{
+		kept: make(chan *packet.Packet, 64),
+	}
+
+	s, err := server.NewServer(&server.Conf{
+		Address:    "127.0.0.1",
+		Port:       "0",
+		PacketSize: 64,
+		CacheCount: 2,
+	}, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Shutdown(context.Background())
+
+	conn := dial(t, s)
+	defer conn.Close()
+
+	// Many more packets than preallocated, none overwrites another.
+	for i := 0; i < 32; i++ {
+		conn.Write([]byte{byte(i)})
+	}
+
+	var kept []*packet.Packet
+	for i := 0; i < 32; i++ {
+		select {
+		case p := <-h.kept:
+			kept = append(kept, p)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("packet %d not received", i)
+		}
+	}
+
+	for i, p := range kept {
+		if p.Size != 1 || p.Payload[0] != byte(i) {
+			t.Fatalf("packet %d holds %v", i, p.Payload[:p.Size])
+		}
+	}
+}
+
+// recorder collects the payloads of each peer.
+type recorder struct {
+	echo
+	mu    sync.Mutex
+	peers map[string][]byte
+	count chan struct{}
+}
+
+func (h *recorder) OnPacket(p *packet.Packet) error {
+	runtime.Gosched()
+
+	h.mu.Lock()
+	h.peers[p.Remote.String()] = append(h.peers[p.Remote.String()], p.Payload[0])
+	h.mu.Unlock()
+
+	p.Release()
+	h.count <- struct{}{}
+	return nil
+}
+
+func TestOrderedDispatch(t *testing.T) {
+	h := &recorder{
+		peers: make(map[string][]byte),
+		count: make(chan struct{}, 1024),
+	}
+
+	s, err := server.NewServer(&server.Conf{
+		Address:    "127.0.0.1",
+		Port:       "0",
+		PacketSize: 64,
+		Pool:       scheduler.New(64, 8),
+		Ordered:    true,
+	}, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Shutdown(context.Background())
+
+	const peers, packets = 4, 50
+
+	for i := 0; i < peers; i++ {
+		conn := dial(t, s)
+		defer conn.Close()
+
+		// Paced, so that the socket buffer never overflows.
+		go func() {
+			for j := 0; j < packets; j++ {
+				conn.Write([]byte{byte(j)})
+				time.Sleep(100 * time.Microsecond)
+			}
+		}()
+	}
+
+	for i := 0; i < peers*packets; i++ {
+		select {
+		case <-h.count:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %d packets, stats %+v", i, s.Stats())
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for peer, got := range h.peers {
+		for j, b := range got {
+			if b != byte(j) {
+				t.Fatalf("%s: packet %d is %d", peer, j, b)
+			}
+		}
+	}
+
+	if stats := s.Stats(); stats.Received != peers*packets || stats.Dropped != 0 {
+		t.Fatalf("stats %+v", stats)
+	}
+}
+
+// stuck blocks every packet until released.
+type stuck struct {
+	echo
+	release chan struct{}
+}
+
+func (h *stuck) OnPacket(p *packet.Packet) error {
+	<-h.release
+	p.Release()
+	return nil
+}
+
+func TestShutdownBlocked(t *testing.T) {
+	for _, pool := range []*scheduler.Pool{nil, scheduler.New(1, 1)} {
+		h := &stuck{
+			release: make(chan struct{}),
+		}
+
+		s, err := server.NewServer(&server.Conf{
+			Address:    "127.0.0.1",
+			Port:       "0",
+			PacketSize: 64,
+			Pool:       pool,
+		}, h)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		conn := dial(t, s)
+		conn.Write([]byte("ping"))
+
+		deadline := time.Now().Add(5 * time.Second)
+		for s.Stats().Received == 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		// A handler that never returns doesn't hold Shutdown past ctx.
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		start := time.Now()
+
+		if err = s.Shutdown(ctx); err != context.DeadlineExceeded {
+			t.Fatalf("Shutdown returned %v", err)
+		}
+		cancel()
+
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Fatalf("Shutdown took %v", elapsed)
+		}
+
+		// OnClose comes once the handler returned.
+		close(h.release)
+
+		if err = s.Shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		if n := atomic.LoadInt32(&h.closed); n != 1 {
+			t.Fatalf("OnClose called %d times", n)
+		}
+
+		conn.Close()
+	}
+}
+
+func TestDropOverflow(t *testing.T) {
+	h := &stuck{
+		release: make(chan struct{}),
+	}
+
+	s, err := server.NewServer(&server.Conf{
+		Address:    "127.0.0.1",
+		Port:       "0",
+		PacketSize: 64,
+		Pool:       scheduler.New(1, 1),
+		Overflow:   server.Drop,
+	}, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn := dial(t, s)
+	defer conn.Close()
+
+	for i := 0; i < 20; i++ {
+		conn.Write([]byte{byte(i)})
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for s.Stats().Received < 20 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	stats := s.Stats()
+	if stats.Received != 20 || stats.Dropped == 0 || stats.Dispatched+stats.Dropped != 20 {
+		t.Fatalf("stats %+v", stats)
+	}
+
+	close(h.release)
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	// A wildcard address is dual-stack, its batches carry IPv6 addresses.
+	for _, address := range []string{"127.0.0.1", ""} {
+		s, _ := start(t, &server.Conf{
+			Address:    address,
+			Port:       "0",
+			PacketSize: 64,
+			BatchSize:  16,
+		})
+
+		port := s.Addr().(*net.UDPAddr).Port
+		conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		const packets = 100
+
+		got := make(chan []byte, packets)
+		go func() {
+			b := make([]byte, 64)
+			for i := 0; i < packets; i++ {
+				n, err := conn.Read(b)
+				if err != nil {
+					close(got)
+					return
+				}
+				got <- append([]byte(nil), b[:n]...)
+			}
+		}()
+
+		for i := 0; i < packets; i++ {
+			conn.Write([]byte{byte(i)})
+		}
+
+		seen := make(map[byte]bool)
+		for b := range got {
+			seen[b[0]] = true
+			if len(seen) == packets {
+				break
+			}
+		}
+
+		if len(seen) != packets {
+			t.Fatalf("%q: %d packets echoed, stats %+v", address, len(seen), s.Stats())
+		}
+
+		conn.Close()
+		if err := s.Shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReusePort(t *testing.T) {
+	s, _ := start(t, &server.Conf{
+		Address:    "127.0.0.1",
+		Port:       "0",
+		PacketSize: 64,
+		Sockets:    4,
+	})
+	defer s.Shutdown(context.Background())
+
+	const peers = 16
+
+	b := make([]byte, 64)
+	for i := 0; i < peers; i++ {
+		conn := dial(t, s)
+
+		conn.Write([]byte("ping"))
+		if n, err := conn.Read(b); err != nil || string(b[:n]) != "ping" {
+			t.Fatalf("peer %d: read %q, %v", i, b[:n], err)
+		}
+		conn.Close()
+	}
+
+	stats := s.Stats()
+	if len(stats.Sockets) != 4 || stats.Received != peers {
+		t.Fatalf("stats %+v", stats)
+	}
+
+	// The kernel spreads peers by hash, and replies leave on any socket.
+	var receiving int
+	var sent uint64
+	for _, ss := range stats.Sockets {
+		if ss.Received > 0 {
+			receiving++
+		}
+		sent += ss.Sent
+	}
+
+	if receiving < 2 || sent != peers {
+		t.Fatalf("stats %+v", stats)
+	}
+}
+
+func TestSocketOptions(t *testing.T) {
+	for _, address := range []string{"127.0.0.1", "::1", ""} {
+		h := &echo{
+			ready: make(chan struct{}),
+		}
+
+		s, err := server.NewServer(&server.Conf{
+			Address:     address,
+			Port:        "0",
+			PacketSize:  64,
+			ReadBuffer:  128 << 10,
+			TOS:         0xb8,
+			TTL:         7,
+			NoBroadcast: address == "::1",
+		}, h)
+		if err != nil {
+			if address == "::1" {
+				t.Log("no IPv6 loopback:", err)
+				continue
+			}
+			t.Fatal(err)
+		}
+
+		opts, err := s.SocketOptions()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		o := opts[0]
+		if o.ReadBuffer < 128<<10 || o.TOS != 0xb8 || o.TTL != 7 || o.Broadcast != (address != "::1") {
+			t.Fatalf("%q: options %+v", address, o)
+		}
+
+		s.Shutdown(context.Background())
+	}
+}
+
+// collector passes the payloads received on.
+type collector struct {
+	echo
+	got chan string
+}
+
+func (h *collector) OnPacket(p *packet.Packet) error {
+	h.got <- string(p.Payload[:p.Size])
+	p.Release()
+	return nil
+}
+
+func TestMulticast(t *testing.T) {
+	lo, err := net.InterfaceByName("lo")
+	if err != nil {
+		t.Skip("no loopback interface:", err)
+	}
+
+	group := server.Group{
+		IP:        net.IPv4(239, 1, 2, 3),
+		Interface: lo,
+	}
+
+	s, _ := start(t, &server.Conf{
+		Network:            "udp4",
+		Address:            "127.0.0.1",
+		Port:               "0",
+		PacketSize:         64,
+		MulticastInterface: "lo",
+		MulticastHops:      1,
+	})
+	defer s.Shutdown(context.Background())
+
+	// Each packet sent to the group is handled once, whatever the number
+	// of sockets.
+	for _, sockets := range []int{1, 4} {
+		h := &collector{
+			got: make(chan string, 16),
+		}
+
+		r, err := server.NewServer(&server.Conf{
+			Network:    "udp4",
+			Address:    "0.0.0.0",
+			Port:       "0",
+			PacketSize: 64,
+			Sockets:    sockets,
+			Groups:     []server.Group{group},
+		}, h)
+		if err != nil {
+			t.Skip("multicast unavailable:", err)
+		}
+
+		to := &net.UDPAddr{IP: group.IP, Port: r.Addr().(*net.UDPAddr).Port}
+
+		for i := 0; i < 3; i++ {
+			s.Send([]byte("tick"), to)
+
+			select {
+			case got := <-h.got:
+				if got != "tick" {
+					t.Fatalf("%d sockets: received %q", sockets, got)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%d sockets: nothing received from the group", sockets)
+			}
+		}
+
+		select {
+		case got := <-h.got:
+			t.Fatalf("%d sockets: received %q more than once", sockets, got)
+		case <-time.After(200 * time.Millisecond):
+		}
+
+		if groups := r.Groups(); len(groups) != 1 {
+			t.Fatalf("%d sockets: joined %v", sockets, groups)
+		}
+
+		if err = r.LeaveGroup(group); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = r.LeaveGroup(group); err != server.ErrNotJoined {
+			t.Fatalf("leaving twice: %v", err)
+		}
+
+		s.Send([]byte("tock"), to)
+		select {
+		case got := <-h.got:
+			t.Fatalf("%d sockets: received %q after leaving", sockets, got)
+		case <-time.After(200 * time.Millisecond):
+		}
+
+		if err = r.JoinGroup(server.Group{IP: net.IPv4(127, 0, 0, 1)}); err != server.ErrNotMulticast {
+			t.Fatalf("joining a unicast address: %v", err)
+		}
+
+		r.Shutdown(context.Background())
+	}
+}
+
+func TestLeaveGroupFailed(t *testing.T) {
+	lo, err := net.InterfaceByName("lo")
+	if err != nil {
+		t.Skip("no loopback interface:", err)
+	}
+
+	group := server.Group{
+		IP:        net.IPv4(239, 1, 2, 4),
+		Interface: lo,
+	}
+
+	r, err := server.NewServer(&server.Conf{
+		Network:    "udp4",
+		Address:    "0.0.0.0",
+		Port:       "0",
+		PacketSize: 64,
+		Groups:     []server.Group{group},
+	}, &collector{got: make(chan string, 16)})
+	if err != nil {
+		t.Skip("multicast unavailable:", err)
+	}
+
+	// Leaving fails once the sockets are closed, the group stays joined.
+	r.Shutdown(context.Background())
+
+	if err = r.LeaveGroup(group); err == nil || err == server.ErrNotJoined {
+		t.Fatalf("leaving on a closed socket: %v", err)
+	}
+
+	if groups := r.Groups(); len(groups) != 1 || !groups[0].IP.Equal(group.IP) {
+		t.Fatalf("joined %v after failing to leave", groups)
+	}
+}